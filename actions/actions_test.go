@@ -3,6 +3,8 @@ package actions
 import (
 	"testing"
 
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/httptest"
 	"github.com/gobuffalo/packr/v2"
 	"github.com/gobuffalo/suite/v3"
 )
@@ -12,6 +14,11 @@ type ActionSuite struct {
 }
 
 func Test_ActionSuite(t *testing.T) {
+	if models.AnonymizationKey == "" {
+		models.AnonymizationKey = "test"
+	}
+	AdminToken = "sekret"
+
 	action, err := suite.NewActionWithFixtures(App(), packr.New("../fixtures", "../fixtures"))
 	if err != nil {
 		t.Fatal(err)
@@ -22,3 +29,10 @@ func Test_ActionSuite(t *testing.T) {
 	}
 	suite.Run(t, as)
 }
+
+// adminJSON is a JSON request to the admin API, authenticated with the shared token
+func (as *ActionSuite) adminJSON(u string, args ...interface{}) *httptest.JSON {
+	req := as.JSON("/v1/tweaser/admin"+u, args...)
+	req.Headers["X-Auth-Token"] = AdminToken
+	return req
+}
@@ -9,7 +9,6 @@ import (
 	contenttype "github.com/gobuffalo/mw-contenttype"
 	forcessl "github.com/gobuffalo/mw-forcessl"
 	paramlogger "github.com/gobuffalo/mw-paramlogger"
-	"github.com/pkg/errors"
 	"github.com/unrolled/secure"
 
 	"github.com/YaleSpinup/tweaser/models"
@@ -45,6 +44,11 @@ var (
 // application.
 func App() *buffalo.App {
 	if app == nil {
+		// anonymous campaigns can't be kept anonymous with an unkeyed hash of the user ID
+		if models.AnonymizationKey == "" {
+			log.Fatal("ANONYMIZATION_KEY or CRYPT_TOKEN must be set")
+		}
+
 		app = buffalo.New(buffalo.Options{
 			Env:          ENV,
 			SessionStore: sessions.Null{},
@@ -66,7 +70,19 @@ func App() *buffalo.App {
 		app.GET("/v1/tweaser/version", VersionHandler)
 
 		userAPI := app.Group("/v1/tweaser")
+		userAPI.Use(limitByIP)
 		userAPI.POST("/responses", ResponsesCreate)
+		userAPI.PUT("/responses/{response_id}", ResponsesUpdate)
+		userAPI.DELETE("/responses/{response_id}", ResponsesDelete)
+		userAPI.POST("/campaigns/{campaign_id}/responses", CampaignsSubmit)
+		userAPI.GET("/campaigns/{campaign_id}/progress", CampaignsGetProgress)
+		userAPI.POST("/sections/{section_id}/responses", SectionsSubmit)
+		userAPI.PUT("/drafts", DraftsUpdate)
+		userAPI.GET("/users/{user_id}/responses", UsersGetOwnResponses)
+		userAPI.GET("/users/{user_id}/export", UsersGetOwnExport)
+		userAPI.POST("/dismissals", DismissalsCreate)
+		userAPI.POST("/optouts", OptOutsCreate)
+		userAPI.DELETE("/optouts", OptOutsDelete)
 
 		adminAPI := app.Group("/v1/tweaser/admin")
 		adminAPI.Use(sharedTokenAuth)
@@ -76,6 +92,17 @@ func App() *buffalo.App {
 		adminAPI.GET("/campaigns/{campaign_id}", CampaignsGet)
 		adminAPI.PUT("/campaigns/{campaign_id}", CampaignsUpdate)
 		adminAPI.GET("/campaigns/{campaign_id}/questions", CampaignsGetQuestions)
+		adminAPI.GET("/campaigns/{campaign_id}/sections", CampaignsGetSections)
+		adminAPI.POST("/campaigns/{campaign_id}/targeting", CampaignsTestTargeting)
+		adminAPI.GET("/campaigns/{campaign_id}/stats", CampaignsGetStats)
+		adminAPI.GET("/campaigns/{campaign_id}/runs", CampaignsGetRuns)
+		adminAPI.GET("/campaigns/{campaign_id}/lists/{list}", CampaignListsGet)
+		adminAPI.POST("/campaigns/{campaign_id}/lists/{list}", CampaignListsUpload)
+		adminAPI.DELETE("/campaigns/{campaign_id}/lists/{list}", CampaignListsDelete)
+
+		adminAPI.GET("/sections/{section_id}", SectionsGet)
+		adminAPI.POST("/sections", SectionsCreate)
+		adminAPI.PUT("/sections/{section_id}", SectionsUpdate)
 
 		adminAPI.GET("/questions", QuestionsList)
 		adminAPI.GET("/questions/{question_id}", QuestionsGet)
@@ -83,14 +110,34 @@ func App() *buffalo.App {
 		adminAPI.PUT("/questions/{question_id}", QuestionsUpdate)
 		adminAPI.GET("/questions/{question_id}/answers", QuestionsGetAnswers)
 		adminAPI.GET("/questions/{question_id}/responses", QuestionsGetResponses)
+		adminAPI.GET("/questions/{question_id}/variants", QuestionsGetVariants)
 
 		adminAPI.GET("/answers", AnswersList)
 		adminAPI.GET("/answers/{answer_id}", AnswersGet)
 		adminAPI.POST("/answers", AnswersCreate)
 		adminAPI.PUT("/answers/{answer_id}", AnswersUpdate)
 
+		adminAPI.GET("/variants/{variant_id}", VariantsGet)
+		adminAPI.POST("/variants", VariantsCreate)
+		adminAPI.PUT("/variants/{variant_id}", VariantsUpdate)
+
+		adminAPI.GET("/optouts", OptOutsList)
+		adminAPI.POST("/optouts/import", OptOutsImport)
+
+		adminAPI.GET("/users/{user_id}/responses", UsersGetResponses)
+		adminAPI.GET("/users/{user_id}/export", UsersExport)
+		adminAPI.DELETE("/users/{user_id}", UsersErase)
+
 		adminAPI.GET("/responses", ResponsesList)
 		adminAPI.GET("/responses/{response_id}", ResponsesGet)
+		adminAPI.GET("/responses/{response_id}/versions", ResponsesGetVersions)
+
+		adminAPI.POST("/tokens/revoke", TokensRevoke)
+
+		adminAPI.GET("/audit", AuditList)
+
+		schedulePurge(app)
+		scheduleRuns(app)
 	}
 
 	return app
@@ -107,15 +154,3 @@ func forceSSL() buffalo.MiddlewareFunc {
 		SSLProxyHeaders: map[string]string{"X-Forwarded-Proto": "https"},
 	})
 }
-
-func sharedTokenAuth(next buffalo.Handler) buffalo.Handler {
-	return func(c buffalo.Context) error {
-		headers, ok := c.Request().Header["X-Auth-Token"]
-		if !ok || len(headers) == 0 || headers[0] != AdminToken {
-			log.Println("Missing or bad token header for request", c.Request().URL)
-			return c.Error(403, errors.New("Forbidden!"))
-		}
-		log.Println("Auth looks good")
-		return next(c)
-	}
-}
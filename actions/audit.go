@@ -0,0 +1,36 @@
+package actions
+
+import (
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+)
+
+// AuditList gets the audit trail, newest first, optionally filtered by action.
+// GET /v1/tweaser/admin/audit[?action=purge]
+func AuditList(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	events := &models.AuditEvents{}
+
+	// Paginate results. Params "page" and "per_page" control pagination.
+	// Default values are "page=1" and "per_page=20".
+	q := tx.PaginateFromParams(c.Params())
+	if action := c.Param("action"); action != "" {
+		q = q.Where("action = ?", action)
+	}
+
+	if err := q.Order("created_at desc").All(events); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Add the paginator to the context so it can be used in the template.
+	c.Set("pagination", q.Paginator)
+
+	return c.Render(200, r.JSON(events))
+}
@@ -0,0 +1,116 @@
+package actions
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/YaleSpinup/tweaser/ratelimit"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// AdminBearerAuth also accepts the admin token in an Authorization: Bearer header
+	AdminBearerAuth = envy.Get("ADMIN_BEARER_AUTH", "") == "true"
+
+	// adminLockout locks a client IP out of the admin API after ADMIN_LOCKOUT_THRESHOLD (default 5) bad
+	// tokens within ADMIN_LOCKOUT_WINDOW (default 15m), for ADMIN_LOCKOUT_DURATION (default 15m)
+	adminLockout = ratelimit.NewLockout(
+		envIntDefault("ADMIN_LOCKOUT_THRESHOLD", 5),
+		envDurationDefault("ADMIN_LOCKOUT_WINDOW", 15*time.Minute),
+		envDurationDefault("ADMIN_LOCKOUT_DURATION", 15*time.Minute),
+	)
+
+	// proxyWarning makes sure running behind a proxy without TRUST_PROXY is only logged once
+	proxyWarning sync.Once
+)
+
+// sharedTokenAuth authenticates admin requests with the shared ADMIN_TOKEN.  Client IPs that send too many
+// bad tokens are locked out for a while, and failures are logged as security events.  Behind a proxy every
+// request comes from the proxy's IP unless TRUST_PROXY is set, so one client's bad tokens would lock out
+// every admin.
+func sharedTokenAuth(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		if !TrustProxy && c.Request().Header.Get("X-Forwarded-For") != "" {
+			proxyWarning.Do(func() {
+				log.Warn("Admin requests are coming through a proxy but TRUST_PROXY isn't set, so admin lockouts apply to every client behind it")
+			})
+		}
+
+		ip := clientIP(c)
+		event := log.WithFields(log.Fields{
+			"event":  "admin_auth",
+			"ip":     ip,
+			"method": c.Request().Method,
+			"path":   c.Request().URL.Path,
+		})
+
+		now := time.Now()
+		if locked, wait := adminLockout.Locked(ip, now); locked {
+			event.WithField("outcome", "locked_out").Warn("Refused admin request from a locked out client")
+			c.Response().Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(wait.Seconds()))))
+			return c.Error(429, errors.New("Too many failed attempts."))
+		}
+
+		token, source := adminToken(c)
+		if reason := checkAdminToken(token); reason != "" {
+			failures, locked := adminLockout.Fail(ip, now)
+			event.WithFields(log.Fields{
+				"outcome":  "failed",
+				"reason":   reason,
+				"source":   source,
+				"failures": failures,
+				"locked":   locked,
+			}).Warn("Failed admin authentication")
+			return c.Error(403, errors.New("Forbidden!"))
+		}
+
+		adminLockout.Succeed(ip)
+		event.WithFields(log.Fields{"outcome": "ok", "source": source}).Debug("Admin authenticated")
+		return next(c)
+	}
+}
+
+// adminToken gets the token from the X-Auth-Token header or, when AdminBearerAuth is set, the
+// Authorization header, along with the header it came from
+func adminToken(c buffalo.Context) (string, string) {
+	headers := c.Request().Header
+	if values, ok := headers["X-Auth-Token"]; ok && len(values) > 0 {
+		return values[0], "X-Auth-Token"
+	}
+
+	if AdminBearerAuth {
+		auth := headers.Get("Authorization")
+		if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+			return strings.TrimSpace(auth[len("Bearer "):]), "Authorization"
+		}
+	}
+
+	return "", ""
+}
+
+// checkAdminToken compares the token to ADMIN_TOKEN in constant time, returning why it was refused or ""
+// if it matches.  Both are hashed first so the comparison doesn't give away the token's length.
+func checkAdminToken(token string) string {
+	if AdminToken == "" {
+		return "admin token not configured"
+	}
+
+	if token == "" {
+		return "missing token"
+	}
+
+	want := sha256.Sum256([]byte(AdminToken))
+	got := sha256.Sum256([]byte(token))
+	if subtle.ConstantTimeCompare(want[:], got[:]) != 1 {
+		return "bad token"
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package actions
+
+import (
+	"strconv"
+
+	"github.com/YaleSpinup/tweaser/helpers"
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+)
+
+// CampaignListsGet gets a paginated list of the user IDs on a campaign's allow or deny list.
+// GET /v1/tweaser/campaigns/{campaign_id}/lists/{list}
+func CampaignListsGet(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	campaign, list, err := findCampaignList(c, tx)
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	entries := models.CampaignUserLists{}
+
+	// Paginate results. Params "page" and "per_page" control pagination.
+	// Default values are "page=1" and "per_page=20".
+	q := tx.PaginateFromParams(c.Params()).Where("campaign_id = ?", campaign.ID).Where("list = ?", list).Order("user_id")
+
+	// Retrieve the list entries from the DB
+	if err := q.All(&entries); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Add the paginator to the context so it can be used in the template.
+	c.Set("pagination", q.Paginator)
+
+	userIDs := []string{}
+	for _, e := range entries {
+		userIDs = append(userIDs, e.UserID)
+	}
+
+	return c.Render(200, r.JSON(struct {
+		List    string   `json:"list"`
+		Total   int      `json:"total"`
+		UserIDs []string `json:"user_ids"`
+	}{
+		List:    list,
+		Total:   q.Paginator.TotalEntriesSize,
+		UserIDs: userIDs,
+	}))
+}
+
+// CampaignListsUpload adds the user IDs in the request body to a campaign's allow or deny list.  The body
+// is CSV with the user ID in the first column or plain text with one user ID per line.  With replace=true
+// the list is cleared before the new user IDs are added.
+// POST /v1/tweaser/campaigns/{campaign_id}/lists/{list}[?replace=true]
+func CampaignListsUpload(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	campaign, list, err := findCampaignList(c, tx)
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	userIDs, err := helpers.ParseUserIDs(c.Request().Body)
+	if err != nil {
+		return c.Render(422, r.JSON(map[string]string{"error": err.Error()}))
+	}
+
+	if replace, _ := strconv.ParseBool(c.Param("replace")); replace {
+		if err := tx.RawQuery("DELETE FROM campaign_user_lists WHERE campaign_id = ? AND list = ?", campaign.ID, list).Exec(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	added, err := models.AddToUserList(tx, campaign.ID, list, userIDs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	total, err := tx.Where("campaign_id = ?", campaign.ID).Where("list = ?", list).Count(&models.CampaignUserList{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(201, r.JSON(struct {
+		List  string `json:"list"`
+		Added int    `json:"added"`
+		Total int    `json:"total"`
+	}{
+		List:  list,
+		Added: added,
+		Total: total,
+	}))
+}
+
+// CampaignListsDelete clears a campaign's allow or deny list.
+// DELETE /v1/tweaser/campaigns/{campaign_id}/lists/{list}
+func CampaignListsDelete(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	campaign, list, err := findCampaignList(c, tx)
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	if err := tx.RawQuery("DELETE FROM campaign_user_lists WHERE campaign_id = ? AND list = ?", campaign.ID, list).Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(200, r.JSON("deleted"))
+}
+
+// findCampaignList finds the campaign and list named by the campaign_id and list parameters
+func findCampaignList(c buffalo.Context, tx *pop.Connection) (*models.Campaign, string, error) {
+	list := c.Param("list")
+	if list != models.AllowList && list != models.DenyList {
+		return nil, "", errors.Errorf("list must be %s or %s", models.AllowList, models.DenyList)
+	}
+
+	// Allocate an empty Campaign
+	campaign := &models.Campaign{}
+
+	// To find the Campaign the parameter campaign_id is used.
+	if err := tx.Find(campaign, c.Param("campaign_id")); err != nil {
+		return nil, "", err
+	}
+
+	return campaign, list, nil
+}
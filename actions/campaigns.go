@@ -1,12 +1,16 @@
 package actions
 
 import (
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/YaleSpinup/tweaser/models"
+	"github.com/YaleSpinup/tweaser/targeting"
 	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 )
 
@@ -23,10 +27,11 @@ func CampaignsList(c buffalo.Context) error {
 
 	q := tx.Q()
 	if active, err := strconv.ParseBool(c.Param("active")); err == nil {
+		now := time.Now()
 		if active {
-			q = q.Where("start_date <= ?", time.Now()).Where("end_date > ?", time.Now())
+			q = q.Where(models.CampaignActiveSQL, now, now)
 		} else {
-			q = q.Where("(start_date > ? or end_date <= ?)", time.Now(), time.Now())
+			q = q.Where("NOT "+models.CampaignActiveSQL, now, now)
 		}
 	}
 
@@ -151,3 +156,255 @@ func CampaignsUpdate(c buffalo.Context) error {
 
 	return c.Render(200, r.JSON(campaign))
 }
+
+// CampaignsTestTargeting evaluates a targeting rule against a sample set of user attributes.  The
+// campaign's own rule is used unless a rule is given in the request body.
+// POST /v1/tweaser/campaigns/{campaign_id}/targeting
+func CampaignsTestTargeting(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	// Allocate an empty Campaign
+	campaign := &models.Campaign{}
+
+	// To find the Campaign the parameter campaign_id is used.
+	if err := tx.Find(campaign, c.Param("campaign_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	req := struct {
+		Rule  *string              `json:"rule"`
+		Attrs targeting.Attributes `json:"attrs"`
+	}{}
+
+	// bind the request body to the test request
+	if err := c.Bind(&req); err != nil {
+		return errors.WithStack(err)
+	}
+
+	rule := campaign.Targeting.String
+	if req.Rule != nil {
+		rule = *req.Rule
+	}
+
+	compiled, err := targeting.Compile(rule)
+	if err != nil {
+		return c.Render(422, r.JSON(map[string]string{"error": err.Error()}))
+	}
+
+	return c.Render(200, r.JSON(struct {
+		Rule  string               `json:"rule"`
+		Attrs targeting.Attributes `json:"attrs"`
+		Match bool                 `json:"match"`
+	}{
+		Rule:  rule,
+		Attrs: req.Attrs,
+		Match: compiled.Match(req.Attrs),
+	}))
+}
+
+// CampaignsGetStats gets the statistics for a campaign by campaign ID.  For sampled campaigns, eligible
+// is the number of users who could have been shown the campaign and sampled is how many of them were.
+// Respondents is the number of users who submitted a response, started the number who submitted a response
+// or saved a draft and completed the number who answered every required question.  For recurring campaigns
+// these are counted for the run given by run_id, or the latest run that has started.
+// GET /v1/tweaser/campaigns/{campaign_id}/stats[?run_id=xxxxx]
+func CampaignsGetStats(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	// Allocate an empty Campaign
+	campaign := &models.Campaign{}
+
+	// To find the Campaign the parameter campaign_id is used.
+	if err := tx.Find(campaign, c.Param("campaign_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	eligible, err := tx.Where("campaign_id = ?", campaign.ID).Count(&models.CampaignSample{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	sampled, err := tx.Where("campaign_id = ?", campaign.ID).Where("sampled = true").Count(&models.CampaignSample{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var run *models.CampaignRun
+	if runID := c.Param("run_id"); runID != "" {
+		run = &models.CampaignRun{}
+		if err := tx.Where("campaign_id = ?", campaign.ID).Find(run, runID); err != nil {
+			return c.Render(404, r.JSON("Run Not Found."))
+		}
+	} else if campaign.Recurring() {
+		if run, err = models.LatestRun(tx, campaign, time.Now()); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	completion := &models.CompletionStats{}
+	if run != nil || !campaign.Recurring() {
+		if completion, err = models.CampaignCompletionStats(tx, campaign.ID, run); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	// counts small enough to single out a respondent are left out of anonymous campaign stats
+	if campaign.Anonymous {
+		min := anonymousMinCount()
+		completion.Suppress(min)
+		completion.Suppressed = append(completion.Suppressed, models.SuppressSmallFields(map[string]*int{"eligible": &eligible, "sampled": &sampled}, min)...)
+		sort.Strings(completion.Suppressed)
+	}
+
+	return c.Render(200, r.JSON(struct {
+		CampaignID    uuid.UUID `json:"campaign_id"`
+		SamplePercent nulls.Int `json:"sample_percent"`
+		Eligible      int       `json:"eligible"`
+		Sampled       int       `json:"sampled"`
+		*models.CompletionStats
+	}{
+		CampaignID:      campaign.ID,
+		SamplePercent:   campaign.SamplePercent,
+		Eligible:        eligible,
+		Sampled:         sampled,
+		CompletionStats: completion,
+	}))
+}
+
+// CampaignsGetRuns gets the runs of a recurring campaign by campaign ID, with the responses collected in each
+// GET /v1/tweaser/campaigns/{campaign_id}/runs
+func CampaignsGetRuns(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	// Allocate an empty Campaign
+	campaign := &models.Campaign{}
+
+	// To find the Campaign the parameter campaign_id is used.
+	if err := tx.Find(campaign, c.Param("campaign_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	report, err := models.RunReport(tx, campaign, time.Now())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// counts small enough to single out a respondent are left out of anonymous campaign reports
+	if campaign.Anonymous {
+		for i := range report {
+			report[i].Suppress(anonymousMinCount())
+		}
+	}
+
+	return c.Render(200, r.JSON(report))
+}
+
+// CampaignsGetSections gets the sections of a campaign by campaign ID, in order, with their questions
+// GET /v1/tweaser/campaigns/{campaign_id}/sections
+func CampaignsGetSections(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	// Allocate an empty Campaign
+	campaign := &models.Campaign{}
+
+	// To find the Campaign the parameter campaign_id is used.
+	if err := tx.Find(campaign, c.Param("campaign_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	sections := models.Sections{}
+	if err := tx.Eager("Questions").Where("campaign_id = ?", campaign.ID).Order("position, created_at").All(&sections); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(200, r.JSON(sections))
+}
+
+// CampaignsSubmit submits a user's responses to several questions in a campaign at once
+// POST /v1/tweaser/campaigns/{campaign_id}/responses
+func CampaignsSubmit(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	campaign := &models.Campaign{}
+	if err := tx.Find(campaign, c.Param("campaign_id")); err != nil {
+		return c.Render(404, r.JSON("Campaign Not Found."))
+	}
+
+	return submitBatch(c, tx, campaign.ID, func(question *models.Question) string {
+		if question.CampaignID != campaign.ID {
+			return "Question is not in this campaign."
+		}
+		return ""
+	}, nil)
+}
+
+// CampaignsGetProgress gets a user's progress through a campaign.  It is authenticated with the token for
+// any question in the campaign served to the user.
+// GET /v1/tweaser/campaigns/{campaign_id}/progress?token=xxxxx&user_id=someguy&question_id=xxxxx
+func CampaignsGetProgress(c buffalo.Context) error {
+	token := c.Param("token")
+	if token == "" {
+		return c.Error(403, errors.New("Unauthorized."))
+	}
+
+	userid := c.Param("user_id")
+	questionID, err := uuid.FromString(c.Param("question_id"))
+	if err != nil {
+		return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	if _, err := validateQuestionToken(tx, token, userid, questionID); err != nil {
+		return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+	}
+
+	question := &models.Question{}
+	if err := tx.Find(question, questionID); err != nil || question.CampaignID.String() != c.Param("campaign_id") {
+		return c.Render(404, r.JSON("Campaign Not Found."))
+	}
+
+	runID, err := models.RunFor(tx, question.CampaignID, time.Now())
+	if err != nil {
+		if errors.Cause(err) == models.ErrNoOpenRun {
+			return c.Render(422, r.JSON("Campaign is between runs."))
+		}
+		return errors.WithStack(err)
+	}
+
+	campaign := &models.Campaign{}
+	if err := tx.Find(campaign, question.CampaignID); err != nil {
+		return errors.WithStack(err)
+	}
+
+	progress, err := models.CampaignProgress(tx, campaign.ID, campaign.RespondentID(userid), runID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(200, r.JSON(progress))
+}
@@ -0,0 +1,81 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+)
+
+// DismissalsCreate dismisses a question for a user, or snoozes it when a snooze duration is given.
+// Dismissing a question that was already dismissed or snoozed replaces the earlier dismissal.
+// POST /v1/tweaser/dismissals?token=xxxxx
+func DismissalsCreate(c buffalo.Context) error {
+	token := c.Param("token")
+	if token == "" {
+		return c.Error(403, errors.New("Unauthorized."))
+	}
+
+	// Allocate an empty Dismissal
+	dismissal := &models.Dismissal{}
+
+	// bind the request body to the new dismissal
+	if err := c.Bind(dismissal); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	if _, err := validateQuestionToken(tx, token, dismissal.UserID, dismissal.QuestionID); err != nil {
+		return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+	}
+
+	if err := tx.Find(&dismissal.Question, dismissal.QuestionID); err != nil {
+		return c.Render(404, r.JSON("Question Not Found."))
+	}
+
+	// dismissals for anonymous campaigns are kept under the same hash as the responses
+	campaign, err := models.CampaignForQuestion(tx, dismissal.QuestionID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	dismissal.UserID = campaign.RespondentID(dismissal.UserID)
+
+	dismissal.SnoozedUntil = nulls.Time{}
+	if dismissal.Snooze != "" {
+		d, err := time.ParseDuration(dismissal.Snooze)
+		if err != nil || d <= 0 {
+			return c.Render(422, r.JSON("Snooze must be a positive duration, like 72h."))
+		}
+		dismissal.SnoozedUntil = nulls.NewTime(time.Now().Add(d))
+	}
+
+	existing := &models.Dismissal{}
+	err = tx.Where("question_id = ?", dismissal.QuestionID).Where("user_id = ?", dismissal.UserID).First(existing)
+	if err == nil {
+		dismissal.ID = existing.ID
+		dismissal.CreatedAt = existing.CreatedAt
+	}
+
+	// Validate the posted data and save it to the database
+	verrs, err := tx.ValidateAndSave(dismissal)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return c.Render(422, r.JSON(verrs))
+	}
+
+	if dismissal.Snoozed() {
+		return c.Render(202, r.JSON("snoozed"))
+	}
+	return c.Render(202, r.JSON("dismissed"))
+}
@@ -0,0 +1,44 @@
+package actions
+
+import (
+	"net/url"
+
+	"github.com/YaleSpinup/tweaser/fixtures"
+	"github.com/YaleSpinup/tweaser/models"
+)
+
+func (as *ActionSuite) Test_Dismissals_Anonymous() {
+	campaign := fixtures.Campaign(as.T(), "anonymous", 0)
+	campaign.Anonymous = true
+	as.NoError(models.DB.Update(campaign))
+	question := fixtures.Question(as.T(), campaign, "private", 0, 0)
+
+	res := as.adminJSON("/questions?user_id=someguy").Get()
+	as.Equal(200, res.Code)
+
+	feed := models.Questions{}
+	res.Bind(&feed)
+	as.Len(feed, 1)
+
+	res = as.JSON("/v1/tweaser/dismissals?token=%s", url.QueryEscape(feed[0].Token)).Post(map[string]interface{}{"user_id": "someguy", "question_id": question.ID})
+	as.Equal(202, res.Code)
+
+	// the impression and the dismissal are stored under the campaign's hash, never the user ID
+	for _, rows := range []interface{}{&models.Impressions{}, &models.Dismissals{}} {
+		count, err := models.DB.Where("user_id = ?", "someguy").Count(rows)
+		as.NoError(err)
+		as.Equal(0, count)
+
+		count, err = models.DB.Where("user_id = ?", campaign.RespondentID("someguy")).Count(rows)
+		as.NoError(err)
+		as.Equal(1, count)
+	}
+
+	// the dismissal is still found under the hash, so the question stays out of the feed
+	res = as.adminJSON("/questions?user_id=someguy").Get()
+	as.Equal(200, res.Code)
+
+	feed = models.Questions{}
+	res.Bind(&feed)
+	as.Len(feed, 0)
+}
@@ -0,0 +1,69 @@
+package actions
+
+import (
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+)
+
+// DraftsUpdate saves a user's unsubmitted text for an input question, replacing any earlier draft.
+// POST the response without text to submit the draft.
+// PUT /v1/tweaser/drafts?token=xxxxx
+func DraftsUpdate(c buffalo.Context) error {
+	token := c.Param("token")
+	if token == "" {
+		return c.Error(403, errors.New("Unauthorized."))
+	}
+
+	// Allocate an empty Draft
+	draft := &models.Draft{}
+
+	// bind the request body to the draft
+	if err := c.Bind(draft); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	if _, err := validateQuestionToken(tx, token, draft.UserID, draft.QuestionID); err != nil {
+		return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+	}
+
+	if err := tx.Find(&draft.Question, draft.QuestionID); err != nil {
+		return c.Render(404, r.JSON("Question Not Found."))
+	}
+
+	// drafts for anonymous campaigns are kept under the same hash as the responses
+	campaign, err := models.CampaignForQuestion(tx, draft.QuestionID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	draft.UserID = campaign.RespondentID(draft.UserID)
+
+	existing, err := models.FindDraft(tx, draft.QuestionID, draft.UserID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if existing != nil {
+		draft.ID = existing.ID
+		draft.CreatedAt = existing.CreatedAt
+	}
+
+	// Validate the posted data and save it to the database
+	verrs, err := tx.ValidateAndSave(draft)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return c.Render(422, r.JSON(verrs))
+	}
+
+	return c.Render(202, r.JSON("saved"))
+}
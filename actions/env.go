@@ -0,0 +1,63 @@
+package actions
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/YaleSpinup/tweaser/ratelimit"
+	"github.com/gobuffalo/envy"
+)
+
+// envInt returns the integer value of an environment variable, or 0 if it isn't set
+func envInt(key string) int {
+	v := envy.Get(key, "")
+	if v == "" {
+		return 0
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("%s must be an integer: %s", key, err)
+	}
+	return i
+}
+
+// envIntDefault returns the integer value of an environment variable, or def if it isn't set
+func envIntDefault(key string, def int) int {
+	if v := envInt(key); v != 0 {
+		return v
+	}
+	return def
+}
+
+// envDuration returns the duration value of an environment variable, or 0 if it isn't set
+func envDuration(key string) time.Duration {
+	v := envy.Get(key, "")
+	if v == "" {
+		return 0
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("%s must be a duration like 12h: %s", key, err)
+	}
+	return d
+}
+
+// envDurationDefault returns the duration value of an environment variable, or def if it isn't set
+func envDurationDefault(key string, def time.Duration) time.Duration {
+	if v := envDuration(key); v != 0 {
+		return v
+	}
+	return def
+}
+
+// envPolicy returns the rate limit policy in an environment variable, or a disabled policy if it isn't set
+func envPolicy(key string) ratelimit.Policy {
+	p, err := ratelimit.ParsePolicy(envy.Get(key, ""))
+	if err != nil {
+		log.Fatalf("%s is invalid: %s", key, err)
+	}
+	return p
+}
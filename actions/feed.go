@@ -0,0 +1,436 @@
+package actions
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/YaleSpinup/tweaser/targeting"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+)
+
+// FeedPolicy is the frequency policy applied to each user's questions across all campaigns
+var FeedPolicy = models.FrequencyPolicy{
+	MaxPerDay:  envInt("MAX_QUESTIONS_PER_DAY"),
+	MaxPerWeek: envInt("MAX_QUESTIONS_PER_WEEK"),
+	Cooldown:   envDuration("QUESTION_COOLDOWN"),
+}
+
+// userFeed builds the list of questions to serve a user.  Users who opted out of everything get an empty
+// list.  Otherwise questions come from enabled, active and open campaigns that aren't in a category the
+// user opted out of and don't exclude the user with their allow or deny lists, whose targeting rules match
+// the user's attributes and whose sample includes the user.  Recurring campaigns are skipped between runs.
+// Questions are left out once the user has answered them (in the current run for recurring campaigns),
+// dismissed them or they have reached their quota.  They are ordered by campaign priority, question
+// priority and then position.  What's left is trimmed to the global and per campaign frequency policies
+// and the optional limit parameter, and then paginated with the page and per_page parameters.  Every
+// question that makes it into the feed is given a response token, the wording assigned to the user and any
+// draft they saved, and is recorded as an impression.  Samples and impressions for anonymous campaigns are
+// stored under the campaign's hash of the user ID, never the user ID itself.
+func userFeed(c buffalo.Context, tx *pop.Connection, userid string) (models.Questions, error) {
+	now := time.Now()
+
+	optedOut, err := models.OptedOutOfEverything(tx, userid)
+	if err != nil {
+		return nil, err
+	}
+
+	// the user isn't served anything to get a question token for, so they get a token to opt back in with
+	if optedOut {
+		token, err := newOptInToken(userid)
+		if err != nil {
+			return nil, err
+		}
+
+		c.Response().Header().Set("X-Opt-In-Token", token)
+		return models.Questions{}, nil
+	}
+
+	campaigns := models.Campaigns{}
+	cq := tx.Where(models.CampaignActiveSQL, now, now).Where("enabled = true")
+	suppressed := models.SuppressionID(userid)
+	cq = cq.Where(models.NotDeniedSQL, userid, suppressed).Where(models.AllowedSQL, userid).Where(models.NotOptedOutSQL, userid, suppressed)
+	if err := cq.All(&campaigns); err != nil {
+		return nil, err
+	}
+
+	if len(campaigns) == 0 {
+		return models.Questions{}, nil
+	}
+
+	attrs := userAttributes(c)
+
+	// rows for anonymous campaigns are stored under the campaign's hash of the user ID, so the user's rows
+	// are looked up under all of them
+	byID := map[uuid.UUID]models.Campaign{}
+	runs := map[uuid.UUID]nulls.UUID{}
+	respondents := []string{userid}
+	var campaignIDs []interface{}
+	for _, campaign := range campaigns {
+		// a rule that no longer compiles only takes its own campaign out of the feed
+		targeted, err := campaign.Targets(attrs)
+		if err != nil {
+			log.Println("Skipping campaign", campaign.ID, "with a targeting rule that doesn't compile:", err)
+			continue
+		}
+
+		if !targeted {
+			continue
+		}
+
+		// keep track of who was eligible for sampled campaigns so we can report on the sample
+		if campaign.SamplePercent.Valid {
+			sampled := campaign.InSample(userid)
+			if err := models.RecordSample(tx, campaign.ID, campaign.RespondentID(userid), sampled); err != nil {
+				return nil, err
+			}
+
+			if !sampled {
+				continue
+			}
+		}
+
+		// recurring campaigns are only served while one of their runs is open
+		if campaign.Recurring() {
+			run, err := models.CurrentRun(tx, &campaign, now)
+			if err != nil {
+				return nil, err
+			}
+
+			if run == nil {
+				continue
+			}
+			runs[campaign.ID] = nulls.NewUUID(run.ID)
+		}
+
+		byID[campaign.ID] = campaign
+		campaignIDs = append(campaignIDs, campaign.ID.String())
+		if campaign.Anonymous {
+			respondents = append(respondents, campaign.RespondentID(userid))
+		}
+	}
+
+	if len(campaignIDs) == 0 {
+		return models.Questions{}, nil
+	}
+
+	questions := models.Questions{}
+	q := tx.Q().Join("campaigns", "campaigns.id = questions.campaign_id")
+	q = q.Where("questions.enabled = true")
+	q = q.Where("questions.campaign_id IN (?)", campaignIDs...)
+	q = q.Where(models.QuestionHasRoomSQL)
+
+	// the most important questions come first, so limit=1 gets the single most important question
+	q = q.Order("campaigns.priority DESC, questions.priority DESC, questions.position ASC, questions.created_at ASC")
+	if err := q.All(&questions); err != nil {
+		return nil, err
+	}
+
+	// leave out the questions already answered, in the open run for recurring campaigns.  Responses to
+	// anonymous campaigns are stored under a hash, so each campaign is checked with its own respondent ID.
+	answered := map[uuid.UUID]bool{}
+	for _, campaign := range byID {
+		ids, err := models.AnsweredInCampaign(tx, campaign.ID, campaign.RespondentID(userid), runs[campaign.ID])
+		if err != nil {
+			return nil, err
+		}
+
+		for id := range ids {
+			answered[id] = true
+		}
+	}
+
+	dismissed, err := models.DismissedQuestions(tx, respondents, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the enabled answers that still have room for each question
+	open := models.Questions{}
+	for _, q := range questions {
+		if answered[q.ID] || dismissed[q.ID] {
+			continue
+		}
+
+		answers := []models.Answer{}
+		err := tx.Where("question_id = ?", q.ID).Where("enabled = true").Where(models.AnswerHasRoomSQL).All(&answers)
+		if err != nil {
+			return nil, err
+		}
+
+		// a choice question with every answer at quota can't be answered anymore
+		if q.Type != "input" && len(answers) == 0 {
+			continue
+		}
+
+		q.Answers = answers
+		open = append(open, q)
+	}
+
+	questions, err = applyFrequencyPolicies(tx, respondents, byID, open, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit, err := strconv.Atoi(c.Param("limit")); err == nil && limit > 0 && limit < len(questions) {
+		questions = questions[:limit]
+	}
+
+	// Paginate what's left, so filtering never leaves a page short while there are more questions.
+	// Params "page" and "per_page" control pagination, default values are "page=1" and "per_page=20".
+	questions = paginate(questions, pop.NewPaginatorFromParams(c.Params()))
+
+	// Generate a token for each question and pick its wording
+	impressions := models.Impressions{}
+	for i, q := range questions {
+		token, err := newQuestionToken(userid, q.ID)
+		if err != nil {
+			return nil, err
+		}
+		questions[i].Token = token
+
+		campaign := byID[q.CampaignID]
+		impression := models.Impression{UserID: campaign.RespondentID(userid), QuestionID: q.ID, CampaignID: q.CampaignID}
+
+		// serve the wording assigned to the user when the question is being A/B tested
+		variant, err := models.AssignVariant(tx, q.ID, campaign.RespondentID(userid))
+		if err != nil {
+			return nil, err
+		}
+
+		if variant != nil {
+			questions[i].Text = variant.Text
+			questions[i].VariantID = &variant.ID
+			impression.VariantID = nulls.NewUUID(variant.ID)
+		}
+
+		impressions = append(impressions, impression)
+	}
+
+	if len(impressions) > 0 {
+		if err := tx.Create(&impressions); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := attachDrafts(tx, userid, byID, questions); err != nil {
+		return nil, err
+	}
+
+	return questions, nil
+}
+
+// paginate returns the page of questions the paginator asks for
+func paginate(questions models.Questions, p *pop.Paginator) models.Questions {
+	if p.Offset >= len(questions) {
+		return models.Questions{}
+	}
+
+	end := p.Offset + p.PerPage
+	if end > len(questions) {
+		end = len(questions)
+	}
+	return questions[p.Offset:end]
+}
+
+// attachDrafts fills in the user's saved drafts so the client can prefill its inputs
+func attachDrafts(tx *pop.Connection, userid string, campaigns map[uuid.UUID]models.Campaign, questions models.Questions) error {
+	if len(questions) == 0 {
+		return nil
+	}
+
+	// drafts for anonymous campaigns are saved under the campaign's hash of the user ID
+	ids := make([]interface{}, 0, len(questions))
+	respondents := []interface{}{userid}
+	respondent := map[uuid.UUID]string{}
+	for _, q := range questions {
+		ids = append(ids, q.ID)
+
+		c := campaigns[q.CampaignID]
+		respondent[q.ID] = c.RespondentID(userid)
+		if c.Anonymous {
+			respondents = append(respondents, respondent[q.ID])
+		}
+	}
+
+	drafts := models.Drafts{}
+	if err := tx.Where("user_id IN (?)", respondents...).Where("question_id IN (?)", ids...).All(&drafts); err != nil {
+		return err
+	}
+
+	text := map[uuid.UUID]string{}
+	for _, d := range drafts {
+		if d.UserID == respondent[d.QuestionID] {
+			text[d.QuestionID] = d.Text
+		}
+	}
+
+	for i, q := range questions {
+		if t, ok := text[q.ID]; ok {
+			questions[i].Draft = &t
+		}
+	}
+	return nil
+}
+
+// feedCampaign is a campaign in the grouped feed, with the user's questions split into its sections
+type feedCampaign struct {
+	ID       uuid.UUID     `json:"id"`
+	Name     string        `json:"name"`
+	Sections []feedSection `json:"sections"`
+}
+
+// feedSection is a page of questions in the grouped feed.  Questions that aren't in a section are
+// grouped in a first section without an ID.
+type feedSection struct {
+	ID          nulls.UUID       `json:"id"`
+	Title       string           `json:"title"`
+	Description nulls.String     `json:"description"`
+	Position    int              `json:"position"`
+	Questions   models.Questions `json:"questions"`
+}
+
+// groupFeed groups a user's questions by campaign and then by section.  Campaigns keep the order they
+// have in the feed, sections are ordered by position and sections without any questions are left out.
+func groupFeed(tx *pop.Connection, questions models.Questions) ([]feedCampaign, error) {
+	grouped := []feedCampaign{}
+	index := map[uuid.UUID]int{}
+	for _, q := range questions {
+		if _, ok := index[q.CampaignID]; ok {
+			continue
+		}
+
+		campaign := models.Campaign{}
+		if err := tx.Find(&campaign, q.CampaignID); err != nil {
+			return nil, err
+		}
+
+		sections := models.Sections{}
+		if err := tx.Where("campaign_id = ?", campaign.ID).Order("position, created_at").All(&sections); err != nil {
+			return nil, err
+		}
+
+		group := feedCampaign{ID: campaign.ID, Name: campaign.Name, Sections: []feedSection{{}}}
+		for _, s := range sections {
+			group.Sections = append(group.Sections, feedSection{ID: nulls.NewUUID(s.ID), Title: s.Title, Description: s.Description, Position: s.Position})
+		}
+
+		index[campaign.ID] = len(grouped)
+		grouped = append(grouped, group)
+	}
+
+	for _, q := range questions {
+		group := &grouped[index[q.CampaignID]]
+		section := &group.Sections[0]
+		for i := range group.Sections {
+			if group.Sections[i].ID == q.SectionID {
+				section = &group.Sections[i]
+				break
+			}
+		}
+		section.Questions = append(section.Questions, q)
+	}
+
+	for i := range grouped {
+		sections := []feedSection{}
+		for _, s := range grouped[i].Sections {
+			if len(s.Questions) > 0 {
+				sections = append(sections, s)
+			}
+		}
+		grouped[i].Sections = sections
+	}
+
+	return grouped, nil
+}
+
+// userAttributes collects the user attributes passed as attrs[name]=value parameters
+func userAttributes(c buffalo.Context) targeting.Attributes {
+	attrs := targeting.Attributes{}
+	for k, v := range c.Request().URL.Query() {
+		if strings.HasPrefix(k, "attrs[") && strings.HasSuffix(k, "]") && len(v) > 0 {
+			attrs[k[len("attrs["):len(k)-1]] = v[0]
+		}
+	}
+	return attrs
+}
+
+// applyFrequencyPolicies drops the questions the user shouldn't see right now, either because they
+// answered or dismissed something too recently or because they've already been served as many questions
+// as the global or campaign caps allow.  Activity is looked up under every respondent ID the user goes by.
+func applyFrequencyPolicies(tx *pop.Connection, respondents []string, campaigns map[uuid.UUID]models.Campaign, questions models.Questions, now time.Time) (models.Questions, error) {
+	last, err := models.LastActivity(tx, respondents)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastAny time.Time
+	for _, t := range last {
+		if t.After(lastAny) {
+			lastAny = t
+		}
+	}
+
+	if FeedPolicy.CoolingDown(lastAny, now) {
+		return models.Questions{}, nil
+	}
+
+	served, err := models.ServedSince(tx, respondents, now.Add(-7*24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	servedToday, err := models.ServedSince(tx, respondents, now.Add(-24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	// apply each campaign's own policy and then the global policy to each question in turn.  Only questions
+	// that pass both are counted against the caps for the questions after them.
+	globalToday, globalWeek := inCampaign(servedToday, uuid.Nil), inCampaign(served, uuid.Nil)
+	campaignToday, campaignWeek := map[uuid.UUID]map[uuid.UUID]bool{}, map[uuid.UUID]map[uuid.UUID]bool{}
+
+	feed := models.Questions{}
+	for _, q := range questions {
+		campaign := campaigns[q.CampaignID]
+		policy := campaign.Policy()
+
+		if policy.CoolingDown(last[campaign.ID], now) {
+			continue
+		}
+
+		if _, ok := campaignToday[campaign.ID]; !ok {
+			campaignToday[campaign.ID] = inCampaign(servedToday, campaign.ID)
+			campaignWeek[campaign.ID] = inCampaign(served, campaign.ID)
+		}
+
+		if len(policy.Allow([]uuid.UUID{q.ID}, campaignToday[campaign.ID], campaignWeek[campaign.ID])) == 0 {
+			continue
+		}
+
+		if len(FeedPolicy.Allow([]uuid.UUID{q.ID}, globalToday, globalWeek)) == 0 {
+			continue
+		}
+
+		campaignToday[campaign.ID][q.ID], campaignWeek[campaign.ID][q.ID] = true, true
+		globalToday[q.ID], globalWeek[q.ID] = true, true
+		feed = append(feed, q)
+	}
+	return feed, nil
+}
+
+// inCampaign returns the set of served questions that belong to the campaign, or all of them for uuid.Nil
+func inCampaign(served map[uuid.UUID]uuid.UUID, campaignID uuid.UUID) map[uuid.UUID]bool {
+	set := map[uuid.UUID]bool{}
+	for q, c := range served {
+		if campaignID == uuid.Nil || c == campaignID {
+			set[q] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,163 @@
+package actions
+
+import (
+	"github.com/YaleSpinup/tweaser/helpers"
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// OptOutsList gets a paginated list of opt outs.
+// GET /v1/tweaser/admin/optouts[?user_id=someguy][&category=xxxxx]
+func OptOutsList(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	optOuts := &models.OptOuts{}
+
+	q := tx.Q()
+	if userid := c.Param("user_id"); userid != "" {
+		q = q.Where("user_id = ?", userid)
+	}
+
+	if category := c.Param("category"); category != "" {
+		q = q.Where("category = ?", category)
+	}
+
+	// Paginate results. Params "page" and "per_page" control pagination.
+	// Default values are "page=1" and "per_page=20".
+	q = q.PaginateFromParams(c.Params())
+
+	// Retrieve all OptOuts from the DB
+	if err := q.All(optOuts); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Add the paginator to the context so it can be used in the template.
+	c.Set("pagination", q.Paginator)
+
+	return c.Render(200, r.JSON(optOuts))
+}
+
+// OptOutsImport opts out the users listed in the request body.  The body is CSV with the user ID in the
+// first column and an optional category in the second, or plain text with one user ID per line.  Users who
+// had already opted out aren't counted as imported.
+// POST /v1/tweaser/admin/optouts/import
+func OptOutsImport(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	rows, err := helpers.ParseRows(c.Request().Body)
+	if err != nil {
+		return c.Render(422, r.JSON(map[string]string{"error": err.Error()}))
+	}
+
+	imported := 0
+	for _, row := range rows {
+		category := ""
+		if len(row) > 1 {
+			category = row[1]
+		}
+
+		added, err := models.OptOutUser(tx, row[0], category)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if added {
+			imported++
+		}
+	}
+
+	return c.Render(201, r.JSON(struct {
+		Imported int `json:"imported"`
+	}{
+		Imported: imported,
+	}))
+}
+
+// OptOutsCreate opts a user out of all campaigns, or only the campaigns in a category.  It is
+// authenticated with the token for any question served to the user.
+// POST /v1/tweaser/optouts?token=xxxxx
+func OptOutsCreate(c buffalo.Context) error {
+	token := c.Param("token")
+	if token == "" {
+		return c.Error(403, errors.New("Unauthorized."))
+	}
+
+	// Allocate an empty OptOut
+	optOut := &models.OptOut{}
+
+	// bind the request body to the new opt out
+	if err := c.Bind(optOut); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	if _, err := validateQuestionToken(tx, token, optOut.UserID, optOut.QuestionID); err != nil {
+		return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+	}
+
+	if _, err := models.OptOutUser(tx, optOut.UserID, optOut.Category); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(202, r.JSON("opted out"))
+}
+
+// OptOutsDelete opts a user back in to all campaigns, or to the campaigns in a category.  It is
+// authenticated with the token for any question served to the user, or, without a question_id, with the
+// opt in token the questions list hands a user who opted out of everything.
+// DELETE /v1/tweaser/optouts?token=xxxxx&user_id=someguy[&question_id=xxxxx][&category=xxxxx]
+func OptOutsDelete(c buffalo.Context) error {
+	token := c.Param("token")
+	if token == "" {
+		return c.Error(403, errors.New("Unauthorized."))
+	}
+
+	userid := c.Param("user_id")
+	if err := limitByToken(c, userid, token); err != nil {
+		return err
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	if c.Param("question_id") == "" {
+		if err := validateOptInToken(tx, token, userid); err != nil {
+			return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+		}
+	} else {
+		questionID, err := uuid.FromString(c.Param("question_id"))
+		if err != nil {
+			return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+		}
+
+		if _, err := validateQuestionToken(tx, token, userid, questionID); err != nil {
+			return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+		}
+	}
+
+	// opting back in also drops the opt out kept from before the user was erased
+	if err := tx.RawQuery("DELETE FROM opt_outs WHERE user_id IN (?, ?) AND category = ?", userid, models.SuppressionID(userid), c.Param("category")).Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(202, r.JSON("opted in"))
+}
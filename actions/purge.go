@@ -0,0 +1,56 @@
+package actions
+
+import (
+	"log"
+	"time"
+
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v6"
+)
+
+// DefaultRetention is the retention policy for campaigns that don't set their own
+var DefaultRetention = models.RetentionPolicy{
+	TextDays:     envInt("TEXT_RETENTION_DAYS"),
+	ResponseDays: envInt("RESPONSE_RETENTION_DAYS"),
+}
+
+// PurgeInterval is how often retention policies are enforced, defaults to once a day
+var PurgeInterval = envDuration("PURGE_INTERVAL")
+
+// schedulePurge registers the purge job with the app's worker and queues its first run
+func schedulePurge(app *buffalo.App) {
+	if PurgeInterval <= 0 {
+		PurgeInterval = 24 * time.Hour
+	}
+
+	if err := app.Worker.Register("purge", purgeJob); err != nil {
+		log.Println("Failed to register the purge job:", err)
+		return
+	}
+
+	if err := app.Worker.PerformIn(worker.Job{Handler: "purge"}, PurgeInterval); err != nil {
+		log.Println("Failed to schedule the purge job:", err)
+	}
+}
+
+// purgeJob applies the retention policies and queues the next run, even if this one failed
+func purgeJob(worker.Args) error {
+	defer func() {
+		if err := app.Worker.PerformIn(worker.Job{Handler: "purge"}, PurgeInterval); err != nil {
+			log.Println("Failed to schedule the purge job:", err)
+		}
+	}()
+
+	return models.DB.Transaction(func(tx *pop.Connection) error {
+		results, err := models.Purge(tx, DefaultRetention, time.Now(), false)
+		if err != nil {
+			log.Println("Failed to purge expired data:", err)
+			return err
+		}
+
+		log.Println("Applied retention policies to", len(results), "campaigns")
+		return nil
+	})
+}
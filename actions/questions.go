@@ -1,17 +1,15 @@
 package actions
 
 import (
-	"time"
-
-	"github.com/YaleSpinup/tweaser/helpers"
 	"github.com/YaleSpinup/tweaser/models"
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/pkg/errors"
 )
 
-// QuestionsList returns the list of questions.
-// /v1/tweaser/questions[?user_id=someguy]
+// QuestionsList returns the list of questions.  With a user_id, it returns the questions to serve that user,
+// grouped by campaign and section with format=grouped.
+// /v1/tweaser/questions[?user_id=someguy][&limit=n][&format=grouped]
 func QuestionsList(c buffalo.Context) error {
 	// Get the DB connection from the context
 	tx, ok := c.Value("tx").(*pop.Connection)
@@ -30,52 +28,19 @@ func QuestionsList(c buffalo.Context) error {
 			return errors.WithStack(err)
 		}
 	} else {
-		campaigns := []models.Campaign{}
-		cq := tx.Select("id").Where("start_date <= ?", time.Now()).Where("end_date > ?", time.Now()).Where("enabled = true")
-		err := cq.All(&campaigns)
-		if err != nil {
-			return c.Render(404, r.JSON([]string{}))
-		}
-
-		var campaignIDs []interface{}
-		for _, c := range campaigns {
-			campaignIDs = append(campaignIDs, c.ID.String())
-		}
-
-		// Paginate results. Params "page" and "per_page" control pagination.
-		// Default values are "page=1" and "per_page=20".
-		q := tx.PaginateFromParams(c.Params())
-		q = q.Where("questions.enabled = true")
-		q = q.Where("questions.campaign_id IN (?)", campaignIDs...)
-		q = q.Where("id NOT in (select question_id FROM responses WHERE user_id = (?))", userid)
-		err = q.All(&questions)
+		feed, err := userFeed(c, tx, userid)
 		if err != nil {
-			return c.Render(404, r.JSON([]string{}))
+			return c.Render(500, r.JSON("Internal server error."))
 		}
 
-		// Generate a token for each question
-		for i, q := range questions {
-			mt := helpers.ModelToken{
-				ID:     q.ID,
-				Secret: CryptToken,
-				UserID: userid,
-			}
-
-			token, err := mt.Generate()
-			if err != nil {
-				return c.Render(500, r.JSON("Internal server error."))
-			}
-
-			questions[i].Token = token
-
-			// Get the enabled answers for the question and append them to the questions response
-			answers := []models.Answer{}
-			err = tx.Where("question_id = ?", q.ID).Where("enabled = true").All(&answers)
+		if c.Param("format") == "grouped" {
+			grouped, err := groupFeed(tx, feed)
 			if err != nil {
 				return c.Render(500, r.JSON("Internal server error."))
 			}
-			questions[i].Answers = answers
+			return c.Render(200, r.JSON(grouped))
 		}
+		questions = feed
 	}
 
 	return c.Render(200, r.JSON(questions))
@@ -121,6 +86,41 @@ func QuestionsGetAnswers(c buffalo.Context) error {
 	return c.Render(200, r.JSON(question.Answers))
 }
 
+// QuestionsGetVariants gets the wording variants for a question by question ID.
+// /v1/tweaser/questions/{question_id}/variants
+func QuestionsGetVariants(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	// Allocate an empty Question
+	question := &models.Question{}
+
+	// To find the Question the parameter question_id is used.
+	if err := tx.Find(question, c.Param("question_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	variants := models.QuestionVariants{}
+	if err := tx.Where("question_id = ?", question.ID).Order("created_at, id").All(&variants); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(200, r.JSON(variants))
+}
+
+// AnonymousMinCount is the smallest answer count reported for an anonymous campaign, defaults to 5
+var AnonymousMinCount = envInt("ANONYMOUS_MIN_COUNT")
+
+func anonymousMinCount() int {
+	if AnonymousMinCount > 0 {
+		return AnonymousMinCount
+	}
+	return 5
+}
+
 // QuestionsGetResponses gets the responses for a question by question ID.
 // /v1/tweaser/questions/{question_id}/responses[?extended=true]
 func QuestionsGetResponses(c buffalo.Context) error {
@@ -130,18 +130,34 @@ func QuestionsGetResponses(c buffalo.Context) error {
 		return errors.WithStack(errors.New("no transaction found"))
 	}
 
+	question := &models.Question{}
+	if err := tx.Eager("Answers").Find(question, c.Param("question_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	campaign := &models.Campaign{}
+	if err := tx.Find(campaign, question.CampaignID); err != nil {
+		return c.Error(404, err)
+	}
+
 	if e := c.Param("extended"); e != "" {
+		if campaign.Anonymous {
+			return c.Error(403, errors.New("Responses to anonymous campaigns are only reported in aggregate."))
+		}
+
 		// Allocate empty Responses model
 		responses := models.Responses{}
 		if err := tx.Eager().Where("question_id = (?)", c.Param("question_id")).All(&responses); err != nil {
 			return c.Error(404, err)
 		}
-		return c.Render(200, r.JSON(responses))
-	}
 
-	question := &models.Question{}
-	if err := tx.Eager("Answers").Find(question, c.Param("question_id")); err != nil {
-		return c.Error(404, err)
+		// reports show the redacted text, the original is only returned with the response itself
+		for i := range responses {
+			if responses[i].RedactedText.Valid {
+				responses[i].Text = responses[i].RedactedText.String
+			}
+		}
+		return c.Render(200, r.JSON(responses))
 	}
 
 	counts := map[string]int{}
@@ -161,12 +177,62 @@ func QuestionsGetResponses(c buffalo.Context) error {
 		answers[id] = a.Text
 	}
 
+	// dismissals are reported separately from responses, snoozes that have expired still count as snoozes
+	dismissed, err := tx.Where("question_id = (?)", question.ID).Where("snoozed_until IS NULL").Count(&models.Dismissal{})
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	snoozed, err := tx.Where("question_id = (?)", question.ID).Where("snoozed_until IS NOT NULL").Count(&models.Dismissal{})
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	variants, err := models.VariantReport(tx, question)
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	redactions, err := models.RedactionReport(tx, question.ID)
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	// counts small enough to single out a respondent are left out of anonymous campaign reports.  Dismissals
+	// and redactions are suppressed too, they are named in the suppressed list with a prefix.
+	var suppressed []string
+	if campaign.Anonymous {
+		min := anonymousMinCount()
+		counts, suppressed = models.SuppressSmallCounts(counts, min)
+		suppressed = append(suppressed, models.SuppressSmallFields(map[string]*int{"dismissed": &dismissed, "snoozed": &snoozed}, min)...)
+
+		var hidden []string
+		redactions, hidden = models.SuppressSmallCounts(redactions, min)
+		for _, name := range hidden {
+			suppressed = append(suppressed, "redactions."+name)
+		}
+
+		for i := range variants {
+			variants[i].Suppress(min)
+		}
+	}
+
 	resp := struct {
-		Count   map[string]int    `json:"count"`
-		Answers map[string]string `json:"answers"`
+		Count      map[string]int        `json:"count"`
+		Suppressed []string              `json:"suppressed,omitempty"`
+		Answers    map[string]string     `json:"answers"`
+		Dismissed  int                   `json:"dismissed"`
+		Snoozed    int                   `json:"snoozed"`
+		Variants   []models.VariantStats `json:"variants,omitempty"`
+		Redactions map[string]int        `json:"redactions,omitempty"`
 	}{
-		Count:   counts,
-		Answers: answers,
+		Count:      counts,
+		Suppressed: suppressed,
+		Answers:    answers,
+		Dismissed:  dismissed,
+		Snoozed:    snoozed,
+		Variants:   variants,
+		Redactions: redactions,
 	}
 	return c.Render(200, r.JSON(resp))
 }
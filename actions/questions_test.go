@@ -1,5 +1,13 @@
 package actions
 
+import (
+	"net/url"
+
+	"github.com/YaleSpinup/tweaser/fixtures"
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gofrs/uuid"
+)
+
 func (as *ActionSuite) Test_Questions_List() {
 	as.Fail("Not Implemented!")
 }
@@ -7,3 +15,80 @@ func (as *ActionSuite) Test_Questions_List() {
 func (as *ActionSuite) Test_Questions_Get() {
 	as.Fail("Not Implemented!")
 }
+
+func (as *ActionSuite) Test_Questions_List_Priority() {
+	low := fixtures.Campaign(as.T(), "low", 1)
+	high := fixtures.Campaign(as.T(), "high", 5)
+
+	later := fixtures.Question(as.T(), low, "later", 10, 0)
+	second := fixtures.Question(as.T(), high, "second", 0, 1)
+	first := fixtures.Question(as.T(), high, "first", 0, 2)
+	first.Priority = 3
+	as.NoError(models.DB.Update(first))
+
+	res := as.adminJSON("/questions?user_id=someguy").Get()
+	as.Equal(200, res.Code)
+
+	feed := models.Questions{}
+	res.Bind(&feed)
+	as.Len(feed, 3)
+	as.Equal([]uuid.UUID{first.ID, second.ID, later.ID}, []uuid.UUID{feed[0].ID, feed[1].ID, feed[2].ID})
+
+	// limit=1 is the single most important question, and is the only one given a token
+	res = as.adminJSON("/questions?user_id=someotherguy&limit=1").Get()
+	as.Equal(200, res.Code)
+
+	feed = models.Questions{}
+	res.Bind(&feed)
+	as.Len(feed, 1)
+	as.Equal(first.ID, feed[0].ID)
+	as.NotEmpty(feed[0].Token)
+
+	count, err := models.DB.Where("user_id = ?", "someotherguy").Count(&models.Impressions{})
+	as.NoError(err)
+	as.Equal(1, count)
+}
+
+func (as *ActionSuite) Test_Questions_List_BrokenTargeting() {
+	broken := fixtures.Campaign(as.T(), "broken", 5)
+	fixtures.Question(as.T(), broken, "hidden", 0, 0)
+
+	// a rule saved before a parser change doesn't go through validation again
+	as.NoError(models.DB.RawQuery("UPDATE campaigns SET targeting = ? WHERE id = ?", "school ==", broken.ID).Exec())
+
+	working := fixtures.Campaign(as.T(), "working", 1)
+	shown := fixtures.Question(as.T(), working, "shown", 0, 0)
+
+	res := as.adminJSON("/questions?user_id=someguy").Get()
+	as.Equal(200, res.Code)
+
+	feed := models.Questions{}
+	res.Bind(&feed)
+	as.Len(feed, 1)
+	as.Equal(shown.ID, feed[0].ID)
+}
+
+func (as *ActionSuite) Test_Questions_List_OptInToken() {
+	campaign := fixtures.Campaign(as.T(), "teasing", 0)
+	fixtures.Question(as.T(), campaign, "anything", 0, 0)
+	_, err := models.OptOutUser(models.DB, "someguy", "")
+	as.NoError(err)
+
+	// a user who opted out of everything isn't served anything, but can still opt back in
+	res := as.adminJSON("/questions?user_id=someguy").Get()
+	as.Equal(200, res.Code)
+	token := res.Header().Get("X-Opt-In-Token")
+	as.NotEmpty(token)
+
+	// the token only works for opting in, and only for the user it was given to
+	as.Equal(403, as.JSON("/v1/tweaser/optouts?token=%s&user_id=someotherguy", url.QueryEscape(token)).Delete().Code)
+	as.Equal(202, as.JSON("/v1/tweaser/optouts?token=%s&user_id=someguy", url.QueryEscape(token)).Delete().Code)
+
+	res = as.adminJSON("/questions?user_id=someguy").Get()
+	as.Equal(200, res.Code)
+	as.Empty(res.Header().Get("X-Opt-In-Token"))
+
+	feed := models.Questions{}
+	res.Bind(&feed)
+	as.Len(feed, 1)
+}
@@ -0,0 +1,109 @@
+package actions
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/YaleSpinup/tweaser/helpers"
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/YaleSpinup/tweaser/ratelimit"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/pkg/errors"
+)
+
+var (
+	// RateLimitIP limits the requests each client IP can make to the user endpoints, like 60/1m
+	RateLimitIP = envPolicy("RATE_LIMIT_IP")
+
+	// RateLimitUser limits the responses each user can submit, like 20/1m
+	RateLimitUser = envPolicy("RATE_LIMIT_USER")
+
+	// TrustProxy takes the client IP from the X-Forwarded-For header set by a proxy in front of the app
+	TrustProxy = envy.Get("TRUST_PROXY", "") == "true"
+
+	ipLimits   = newRateLimitStore()
+	userLimits = newRateLimitStore()
+)
+
+// newRateLimitStore creates the store named by RATE_LIMIT_STORE, memory by default or db to share
+// buckets between replicas
+func newRateLimitStore() ratelimit.Store {
+	switch store := envy.Get("RATE_LIMIT_STORE", "memory"); store {
+	case "memory":
+		return ratelimit.NewMemory()
+	case "db":
+		return models.RateLimitStore{DB: models.DB}
+	default:
+		log.Fatalf("RATE_LIMIT_STORE must be memory or db, not %s", store)
+		return nil
+	}
+}
+
+// limitByIP refuses requests from client IPs that are over RateLimitIP
+func limitByIP(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		if err := takeToken(c, ipLimits, "ip:"+clientIP(c), RateLimitIP); err != nil {
+			return err
+		}
+		return next(c)
+	}
+}
+
+// limitByUser refuses the request when the user is over RateLimitUser
+func limitByUser(c buffalo.Context, userID string) error {
+	return takeToken(c, userLimits, "user:"+userID, RateLimitUser)
+}
+
+// limitByToken charges the user's bucket for a request authenticated with a question token, once the token
+// passes the checks that don't need the slow comparison.  Until then the request has only been counted
+// against the client IP, so sending junk tokens in someone else's name can't use up their bucket.
+func limitByToken(c buffalo.Context, userID, token string) error {
+	if err := helpers.CheckTokenFormat(token); err != nil {
+		return c.Error(403, errors.New("Unauthorized. Invalid Token."))
+	}
+	return limitByUser(c, userID)
+}
+
+// takeToken takes a token from the key's bucket, rendering a 429 with a Retry-After header when the bucket
+// is empty.  Requests are let through if the store fails, so an outage doesn't take the endpoints down.
+func takeToken(c buffalo.Context, store ratelimit.Store, key string, p ratelimit.Policy) error {
+	if !p.Enabled() {
+		return nil
+	}
+
+	ok, wait, err := store.Take(key, p, time.Now())
+	if err != nil {
+		log.Println("Failed to check rate limit for", key, err)
+		return nil
+	}
+
+	if !ok {
+		log.Println("Rate limit reached for", key)
+		c.Response().Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(wait.Seconds()))))
+		return c.Error(429, errors.New("Too many requests."))
+	}
+	return nil
+}
+
+// clientIP is the IP of the client making the request.  Behind a trusted proxy it is the last address in
+// X-Forwarded-For, the one the proxy added, since the ones before it are whatever the client sent.
+func clientIP(c buffalo.Context) string {
+	req := c.Request()
+	if TrustProxy {
+		if xff := req.Header.Get("X-Forwarded-For"); xff != "" {
+			hops := strings.Split(xff, ",")
+			return strings.TrimSpace(hops[len(hops)-1])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
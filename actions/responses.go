@@ -1,14 +1,20 @@
 package actions
 
 import (
+	"time"
+
 	"github.com/YaleSpinup/tweaser/helpers"
 	"github.com/YaleSpinup/tweaser/models"
 	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 )
 
-// ResponsesList default implementation.
+// ResponsesList default implementation.  Responses to anonymous campaigns are only reported in aggregate, so
+// they are left out.
 func ResponsesList(c buffalo.Context) error {
 	// Get the DB connection from the context
 	tx, ok := c.Value("tx").(*pop.Connection)
@@ -23,7 +29,7 @@ func ResponsesList(c buffalo.Context) error {
 	q := tx.PaginateFromParams(c.Params())
 
 	// Retrieve all Responses from the DB
-	if err := q.All(responses); err != nil {
+	if err := q.Where(models.NotAnonymousSQL).All(responses); err != nil {
 		return errors.WithStack(err)
 	}
 
@@ -33,7 +39,7 @@ func ResponsesList(c buffalo.Context) error {
 	return c.Render(200, r.JSON(responses))
 }
 
-// ResponsesGet default implementation.
+// ResponsesGet default implementation.  Responses to anonymous campaigns are refused.
 func ResponsesGet(c buffalo.Context) error {
 	// Get the DB connection from the context
 	tx, ok := c.Value("tx").(*pop.Connection)
@@ -49,6 +55,10 @@ func ResponsesGet(c buffalo.Context) error {
 		return c.Error(404, err)
 	}
 
+	if err := refuseAnonymous(c, tx, response.QuestionID); err != nil {
+		return err
+	}
+
 	return c.Render(200, r.JSON(response))
 }
 
@@ -67,14 +77,48 @@ func ResponsesCreate(c buffalo.Context) error {
 		return errors.WithStack(err)
 	}
 
-	mt := helpers.ModelToken{
-		ID:     response.QuestionID,
-		Secret: CryptToken,
-		UserID: response.UserID,
+	if err := limitByToken(c, response.UserID, token); err != nil {
+		return err
 	}
-	err := mt.Validate(token)
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	verrs, err := createResponse(tx, token, response)
 	if err != nil {
-		return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+		if rerr, ok := errors.Cause(err).(*responseError); ok {
+			return c.Render(rerr.status, r.JSON(rerr.message))
+		}
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return c.Render(422, r.JSON(verrs))
+	}
+
+	return c.Render(202, r.JSON("submitted"))
+}
+
+// ResponseEditWindow is how long after submitting a response a user can edit or retract it.  Without one,
+// responses can be changed until their campaign closes.
+var ResponseEditWindow = envDuration("RESPONSE_EDIT_WINDOW")
+
+// ResponsesUpdate lets a user change their response.  The version it replaces is kept for audit.
+// PUT /v1/tweaser/responses/{response_id}?token=xxxxx
+func ResponsesUpdate(c buffalo.Context) error {
+	// Allocate an empty Response for the changes
+	update := &models.Response{}
+
+	// bind the request body to the changes
+	if err := c.Bind(update); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := limitByToken(c, update.UserID, c.Param("token")); err != nil {
+		return err
 	}
 
 	// Get the DB connection from the context
@@ -83,12 +127,27 @@ func ResponsesCreate(c buffalo.Context) error {
 		return errors.WithStack(errors.New("no transaction found"))
 	}
 
-	if err = tx.Find(&response.Question, response.QuestionID); err != nil {
-		return c.Render(404, r.JSON("Question Not Found."))
+	response, err := editableResponse(c, tx, update.UserID)
+	if err != nil {
+		if rerr, ok := errors.Cause(err).(*responseError); ok {
+			return c.Render(rerr.status, r.JSON(rerr.message))
+		}
+		return errors.WithStack(err)
 	}
 
-	// Validate the posted data and save it to the database
-	verrs, err := tx.ValidateAndCreate(response)
+	if err := models.ArchiveResponse(tx, response, models.VersionUpdated); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := tx.Find(&response.Question, response.QuestionID); err != nil {
+		return errors.WithStack(err)
+	}
+
+	response.Text = update.Text
+	response.Answers = update.Answers
+	response.Version++
+
+	verrs, err := tx.ValidateAndUpdate(response)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -97,5 +156,314 @@ func ResponsesCreate(c buffalo.Context) error {
 		return c.Render(422, r.JSON(verrs))
 	}
 
+	// replace the answers selected in the earlier version
+	if err := tx.RawQuery("DELETE FROM response_answers WHERE response_id = ?", response.ID).Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, a := range response.Answers {
+		if err := tx.Create(&models.ResponseAnswer{ResponseID: response.ID, AnswerID: a.ID}); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return c.Render(202, r.JSON("updated"))
+}
+
+// ResponsesDelete lets a user retract their response.  The retracted version is kept for audit, and the
+// user's completion of the campaign is removed if they no longer have every required question answered.
+// DELETE /v1/tweaser/responses/{response_id}?token=xxxxx&user_id=someguy
+func ResponsesDelete(c buffalo.Context) error {
+	if err := limitByToken(c, c.Param("user_id"), c.Param("token")); err != nil {
+		return err
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	response, err := editableResponse(c, tx, c.Param("user_id"))
+	if err != nil {
+		if rerr, ok := errors.Cause(err).(*responseError); ok {
+			return c.Render(rerr.status, r.JSON(rerr.message))
+		}
+		return errors.WithStack(err)
+	}
+
+	if err := models.ArchiveResponse(tx, response, models.VersionRetracted); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := tx.RawQuery("DELETE FROM response_answers WHERE response_id = ?", response.ID).Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := tx.Destroy(response); err != nil {
+		return errors.WithStack(err)
+	}
+
+	campaign, err := models.CampaignForQuestion(tx, response.QuestionID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := models.RecheckCompletion(tx, campaign.ID, response.UserID, response.RunID); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(202, r.JSON("retracted"))
+}
+
+// ResponsesGetVersions gets the earlier versions of a response, oldest first
+// GET /v1/tweaser/admin/responses/{response_id}/versions
+func ResponsesGetVersions(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	versions := models.ResponseVersions{}
+	if err := tx.Where("response_id = ?", c.Param("response_id")).Order("version").All(&versions); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// versions are kept after a response is retracted, so the campaign is found through them
+	if len(versions) > 0 {
+		if err := refuseAnonymous(c, tx, versions[0].QuestionID); err != nil {
+			return err
+		}
+	}
+
+	return c.Render(200, r.JSON(versions))
+}
+
+// refuseAnonymous returns a 403 when the question belongs to an anonymous campaign, whose responses are only
+// reported in aggregate
+func refuseAnonymous(c buffalo.Context, tx *pop.Connection, questionID uuid.UUID) error {
+	campaign, err := models.CampaignForQuestion(tx, questionID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if campaign.Anonymous {
+		return c.Error(403, errors.New("Responses to anonymous campaigns are only reported in aggregate."))
+	}
+
+	return nil
+}
+
+// editableResponse finds the response being edited or retracted and checks that it belongs to the user, that
+// the token is a valid one for the user and its question and that it can still be changed.  Any unexpired,
+// unrevoked token for the question will do, so a response can still be changed after the token that
+// submitted it expires.
+func editableResponse(c buffalo.Context, tx *pop.Connection, userID string) (*models.Response, error) {
+	token := c.Param("token")
+	if token == "" {
+		return nil, &responseError{status: 403, message: "Unauthorized."}
+	}
+
+	response := &models.Response{}
+	if err := tx.Find(response, c.Param("response_id")); err != nil {
+		return nil, &responseError{status: 404, message: "Response Not Found."}
+	}
+
+	campaign, err := models.CampaignForQuestion(tx, response.QuestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.UserID != campaign.RespondentID(userID) {
+		return nil, &responseError{status: 404, message: "Response Not Found."}
+	}
+
+	if _, err := validateQuestionToken(tx, token, userID, response.QuestionID); err != nil {
+		return nil, &responseError{status: 403, message: "Unauthorized. Invalid Token."}
+	}
+
+	deadline, err := models.EditDeadline(tx, response, ResponseEditWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	if !time.Now().Before(deadline) {
+		return nil, &responseError{status: 422, message: "Response can no longer be changed."}
+	}
+	return response, nil
+}
+
+// responseError is a problem with a submitted response that the user can fix, with the status to report it with
+type responseError struct {
+	status  int
+	message string
+}
+
+func (e *responseError) Error() string {
+	return e.message
+}
+
+// createResponse checks the token for a response and saves it.  Problems with the request come back as a
+// *responseError and problems with the response itself as validation errors.
+func createResponse(tx *pop.Connection, token string, response *models.Response) (*validate.Errors, error) {
+	mt, err := validateQuestionToken(tx, token, response.UserID, response.QuestionID)
+	if err != nil {
+		return nil, &responseError{status: 403, message: "Unauthorized. Invalid Token."}
+	}
+
+	// each token can only submit one response, so it can't be replayed in a later run
+	fresh, err := models.UseToken(tx, mt.JTI, response.QuestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fresh {
+		return nil, &responseError{status: 403, message: "Unauthorized. Token has already been used."}
+	}
+	response.TokenID = nulls.NewUUID(mt.JTI)
+
+	if err := tx.Find(&response.Question, response.QuestionID); err != nil {
+		return nil, &responseError{status: 404, message: "Question Not Found."}
+	}
+
+	// anonymous campaigns only store a hash of the user ID
+	campaign := &models.Campaign{}
+	if err := tx.Find(campaign, response.Question.CampaignID); err != nil {
+		return nil, err
+	}
+	response.UserID = campaign.RespondentID(response.UserID)
+
+	// record the wording the user was served.  A variant sent back by the client has to be that one, so
+	// clients can't skew the variant report.
+	variant, err := models.AssignVariant(tx, response.QuestionID, response.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	served := nulls.UUID{}
+	if variant != nil {
+		served = nulls.NewUUID(variant.ID)
+	}
+
+	if response.VariantID.Valid && response.VariantID != served {
+		return nil, &responseError{status: 422, message: "Variant was not served to the user."}
+	}
+	response.VariantID = served
+
+	// an input response without text submits the user's draft
+	if response.Question.Type == "input" && response.Text == "" {
+		draft, err := models.FindDraft(tx, response.QuestionID, response.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		if draft != nil {
+			response.Text = draft.Text
+		}
+	}
+
+	// responses to recurring campaigns belong to the run that is open now
+	runID, err := models.RunFor(tx, campaign.ID, time.Now())
+	if err != nil {
+		if errors.Cause(err) == models.ErrNoOpenRun {
+			return nil, &responseError{status: 422, message: "Campaign is between runs."}
+		}
+		return nil, err
+	}
+	response.RunID = runID
+
+	// Validate the posted data and save it to the database
+	return tx.ValidateAndCreate(response)
+}
+
+// responseSubmission is one response in a request that submits several at once, along with its token
+type responseSubmission struct {
+	Token string `json:"token"`
+	models.Response
+}
+
+// submitBatch saves a batch of responses from one user, each with the token for its question.  Either every
+// response is saved or none are: a failure renders a 422, which rolls back the request's transaction, with
+// the errors for each response that couldn't be saved keyed by question ID.  The check function can reject a
+// response based on its question before it's saved, and the optional after function can report errors for
+// questions once the whole batch is in.  A batch can't have more responses than the campaign has questions,
+// and each response takes a token from the user's rate limit bucket, since each one checks a token.
+func submitBatch(c buffalo.Context, tx *pop.Connection, campaignID uuid.UUID, check func(*models.Question) string, after func(userID string) (map[string]*validate.Errors, error)) error {
+	req := struct {
+		UserID    string               `json:"user_id"`
+		Responses []responseSubmission `json:"responses"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		return c.Render(400, r.JSON("Bad request."))
+	}
+
+	if req.UserID == "" || len(req.Responses) == 0 {
+		return c.Render(422, r.JSON("A user_id and at least one response are required."))
+	}
+
+	questions, err := tx.Where("campaign_id = ?", campaignID).Count(&models.Questions{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if len(req.Responses) > questions {
+		return c.Render(422, r.JSON("A batch can't have more responses than the campaign has questions."))
+	}
+
+	failed := map[string]*validate.Errors{}
+	for i := range req.Responses {
+		response := &req.Responses[i].Response
+		response.UserID = req.UserID
+		key := response.QuestionID.String()
+
+		if _, ok := failed[key]; ok {
+			continue
+		}
+
+		// a malformed token fails in createResponse without charging the user
+		if helpers.CheckTokenFormat(req.Responses[i].Token) == nil {
+			if err := limitByUser(c, req.UserID); err != nil {
+				return err
+			}
+		}
+
+		verrs := validate.NewErrors()
+		question := models.Question{}
+		if err := tx.Find(&question, response.QuestionID); err != nil {
+			verrs.Add("question_id", "Question Not Found.")
+		} else if msg := check(&question); msg != "" {
+			verrs.Add("question_id", msg)
+		} else {
+			var err error
+			verrs, err = createResponse(tx, req.Responses[i].Token, response)
+			if err != nil {
+				rerr, ok := errors.Cause(err).(*responseError)
+				if !ok {
+					return errors.WithStack(err)
+				}
+
+				verrs = validate.NewErrors()
+				verrs.Add("response", rerr.message)
+			}
+		}
+
+		if verrs.HasAny() {
+			failed[key] = verrs
+		}
+	}
+
+	if len(failed) == 0 && after != nil {
+		var err error
+		if failed, err = after(req.UserID); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return c.Render(422, r.JSON(failed))
+	}
+
 	return c.Render(202, r.JSON("submitted"))
 }
@@ -1,5 +1,12 @@
 package actions
 
+import (
+	"net/url"
+
+	"github.com/YaleSpinup/tweaser/fixtures"
+	"github.com/YaleSpinup/tweaser/models"
+)
+
 func (as *ActionSuite) Test_Responses_List() {
 	as.Fail("Not Implemented!")
 }
@@ -15,3 +22,200 @@ func (as *ActionSuite) Test_Responses_Create() {
 func (as *ActionSuite) Test_Responses_Update() {
 	as.Fail("Not Implemented!")
 }
+
+// batchErrors is the body of a rejected batch, the validation errors for each question
+type batchErrors map[string]struct {
+	Errors map[string][]string `json:"errors"`
+}
+
+// batchItem is one response in a batch request
+func (as *ActionSuite) batchItem(userID string, question *models.Question, text string) map[string]interface{} {
+	token, err := newQuestionToken(userID, question.ID)
+	as.NoError(err)
+	return map[string]interface{}{"token": token, "question_id": question.ID, "text": text}
+}
+
+func (as *ActionSuite) Test_Responses_SubmitBatch() {
+	campaign := fixtures.Campaign(as.T(), "batch", 0)
+	first := fixtures.Question(as.T(), campaign, "first", 0, 0)
+	second := fixtures.Question(as.T(), campaign, "second", 0, 1)
+
+	res := as.JSON("/v1/tweaser/campaigns/%s/responses", campaign.ID).Post(map[string]interface{}{
+		"user_id":   "someguy",
+		"responses": []interface{}{as.batchItem("someguy", first, "yes"), as.batchItem("someguy", second, "no")},
+	})
+	as.Equal(202, res.Code)
+
+	count, err := models.DB.Where("user_id = ?", "someguy").Count(&models.Responses{})
+	as.NoError(err)
+	as.Equal(2, count)
+}
+
+func (as *ActionSuite) Test_Responses_SubmitBatchMixed() {
+	campaign := fixtures.Campaign(as.T(), "batch", 0)
+	valid := fixtures.Question(as.T(), campaign, "valid", 0, 0)
+	missing := fixtures.Question(as.T(), campaign, "missing text", 0, 1)
+	fixtures.Question(as.T(), campaign, "unanswered", 0, 2)
+	elsewhere := fixtures.Question(as.T(), fixtures.Campaign(as.T(), "other", 0), "elsewhere", 0, 0)
+
+	res := as.JSON("/v1/tweaser/campaigns/%s/responses", campaign.ID).Post(map[string]interface{}{
+		"user_id": "someguy",
+		"responses": []interface{}{
+			as.batchItem("someguy", valid, "yes"),
+			as.batchItem("someguy", missing, ""),
+			as.batchItem("someguy", elsewhere, "yes"),
+		},
+	})
+	as.Equal(422, res.Code)
+
+	// errors are reported for each response that failed, keyed by question
+	failed := batchErrors{}
+	res.Bind(&failed)
+	as.Len(failed, 2)
+	as.Contains(failed, missing.ID.String())
+	as.Equal([]string{"Question is not in this campaign."}, failed[elsewhere.ID.String()].Errors["question_id"])
+	as.NotContains(failed, valid.ID.String())
+
+	// the valid response was rolled back with the rest of the batch
+	count, err := models.DB.Where("user_id = ?", "someguy").Count(&models.Responses{})
+	as.NoError(err)
+	as.Equal(0, count)
+
+	count, err = models.DB.Count(&models.TokenUses{})
+	as.NoError(err)
+	as.Equal(0, count)
+}
+
+func (as *ActionSuite) Test_Responses_SubmitBatchDuplicateTokens() {
+	campaign := fixtures.Campaign(as.T(), "batch", 0)
+	question := fixtures.Question(as.T(), campaign, "once", 0, 0)
+	fixtures.Question(as.T(), campaign, "unanswered", 0, 1)
+
+	item := as.batchItem("someguy", question, "yes")
+	res := as.JSON("/v1/tweaser/campaigns/%s/responses", campaign.ID).Post(map[string]interface{}{
+		"user_id":   "someguy",
+		"responses": []interface{}{item, item},
+	})
+	as.Equal(422, res.Code)
+
+	failed := batchErrors{}
+	res.Bind(&failed)
+	as.Equal([]string{"Unauthorized. Token has already been used."}, failed[question.ID.String()].Errors["response"])
+
+	count, err := models.DB.Where("user_id = ?", "someguy").Count(&models.Responses{})
+	as.NoError(err)
+	as.Equal(0, count)
+}
+
+func (as *ActionSuite) Test_Responses_SubmitBatchTooLarge() {
+	campaign := fixtures.Campaign(as.T(), "batch", 0)
+	question := fixtures.Question(as.T(), campaign, "only", 0, 0)
+
+	// a batch can't check more tokens than the campaign has questions
+	res := as.JSON("/v1/tweaser/campaigns/%s/responses", campaign.ID).Post(map[string]interface{}{
+		"user_id":   "someguy",
+		"responses": []interface{}{as.batchItem("someguy", question, "yes"), as.batchItem("someguy", question, "no")},
+	})
+	as.Equal(422, res.Code)
+
+	count, err := models.DB.Count(&models.TokenUses{})
+	as.NoError(err)
+	as.Equal(0, count)
+}
+
+func (as *ActionSuite) Test_Responses_DeleteRecheckCompletion() {
+	campaign := fixtures.Campaign(as.T(), "retract", 0)
+	question := fixtures.Question(as.T(), campaign, "only", 0, 0)
+
+	item := as.batchItem("someguy", question, "yes")
+	res := as.JSON("/v1/tweaser/responses?token=%s", url.QueryEscape(item["token"].(string))).Post(map[string]interface{}{"user_id": "someguy", "question_id": question.ID, "text": "yes"})
+	as.Equal(202, res.Code)
+
+	response := &models.Response{}
+	as.NoError(models.DB.Where("user_id = ?", "someguy").First(response))
+
+	count, err := models.DB.Where("campaign_id = ?", campaign.ID).Count(&models.CampaignCompletions{})
+	as.NoError(err)
+	as.Equal(1, count)
+
+	res = as.JSON("/v1/tweaser/responses/%s?token=%s&user_id=someguy", response.ID, url.QueryEscape(item["token"].(string))).Delete()
+	as.Equal(202, res.Code)
+
+	count, err = models.DB.Where("campaign_id = ?", campaign.ID).Count(&models.CampaignCompletions{})
+	as.NoError(err)
+	as.Equal(0, count)
+}
+
+func (as *ActionSuite) Test_Responses_DeleteWithNewToken() {
+	campaign := fixtures.Campaign(as.T(), "retract", 0)
+	question := fixtures.Question(as.T(), campaign, "only", 0, 0)
+
+	item := as.batchItem("someguy", question, "yes")
+	res := as.JSON("/v1/tweaser/responses?token=%s", url.QueryEscape(item["token"].(string))).Post(map[string]interface{}{"user_id": "someguy", "question_id": question.ID, "text": "yes"})
+	as.Equal(202, res.Code)
+
+	response := &models.Response{}
+	as.NoError(models.DB.Where("user_id = ?", "someguy").First(response))
+
+	// a token for someone else's response is refused
+	other, err := newQuestionToken("someotherguy", question.ID)
+	as.NoError(err)
+	res = as.JSON("/v1/tweaser/responses/%s?token=%s&user_id=someotherguy", response.ID, url.QueryEscape(other)).Delete()
+	as.Equal(404, res.Code)
+
+	// any valid token for the user and question will do, not just the one that submitted the response
+	token, err := newQuestionToken("someguy", question.ID)
+	as.NoError(err)
+	res = as.JSON("/v1/tweaser/responses/%s?token=%s&user_id=someguy", response.ID, url.QueryEscape(token)).Delete()
+	as.Equal(202, res.Code)
+}
+
+func (as *ActionSuite) Test_Responses_AnonymousRefused() {
+	campaign := fixtures.Campaign(as.T(), "plain", 0)
+	anonymous := &models.Campaign{Name: "anonymous", StartDate: campaign.StartDate, EndDate: campaign.EndDate, Enabled: true, Anonymous: true}
+	as.NoError(models.DB.Create(anonymous))
+
+	plain := &models.Response{UserID: "someguy", QuestionID: fixtures.Question(as.T(), campaign, "plain", 0, 0).ID, Text: "named"}
+	as.NoError(models.DB.Create(plain))
+	hidden := &models.Response{UserID: anonymous.RespondentID("someguy"), QuestionID: fixtures.Question(as.T(), anonymous, "hidden", 0, 0).ID, Text: "secret"}
+	as.NoError(models.DB.Create(hidden))
+	as.NoError(models.ArchiveResponse(models.DB, hidden, models.VersionUpdated))
+
+	responses := models.Responses{}
+	res := as.adminJSON("/v1/tweaser/admin/responses").Get()
+	as.Equal(200, res.Code)
+	res.Bind(&responses)
+	as.Len(responses, 1)
+	as.Equal(plain.ID, responses[0].ID)
+
+	as.Equal(200, as.adminJSON("/v1/tweaser/admin/responses/%s", plain.ID).Get().Code)
+	as.Equal(403, as.adminJSON("/v1/tweaser/admin/responses/%s", hidden.ID).Get().Code)
+	as.Equal(403, as.adminJSON("/v1/tweaser/admin/responses/%s/versions", hidden.ID).Get().Code)
+}
+
+func (as *ActionSuite) Test_Responses_HistoryTokenOnRequest() {
+	campaign := fixtures.Campaign(as.T(), "history", 0)
+	first := fixtures.Question(as.T(), campaign, "first", 0, 0)
+	second := fixtures.Question(as.T(), campaign, "second", 0, 1)
+
+	for _, q := range []*models.Question{first, second} {
+		as.NoError(models.DB.Create(&models.Response{UserID: "someguy", QuestionID: q.ID, Text: "yes"}))
+	}
+
+	response := &models.Response{}
+	as.NoError(models.DB.Where("question_id = ?", first.ID).First(response))
+
+	token, err := newQuestionToken("someguy", second.ID)
+	as.NoError(err)
+
+	// every editable response is marked, but only the one asked for gets a token
+	history := []models.HistoryEntry{}
+	res := as.JSON("/v1/tweaser/users/someguy/responses?token=%s&question_id=%s&edit=%s", url.QueryEscape(token), second.ID, response.ID).Get()
+	as.Equal(200, res.Code)
+	res.Bind(&history)
+	as.Len(history, 2)
+	for _, entry := range history {
+		as.True(entry.Editable)
+		as.Equal(entry.ID == response.ID, entry.Token != "")
+	}
+}
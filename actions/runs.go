@@ -0,0 +1,53 @@
+package actions
+
+import (
+	"log"
+	"time"
+
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v6"
+)
+
+// RunInterval is how often the runs of recurring campaigns are created ahead of time, defaults to every 15 minutes
+var RunInterval = envDuration("RUN_INTERVAL")
+
+// scheduleRuns registers the job that creates the runs of recurring campaigns and queues its first run.  Runs
+// are created far enough ahead that each one exists before it opens, even if a job runs late.
+func scheduleRuns(app *buffalo.App) {
+	if RunInterval <= 0 {
+		RunInterval = 15 * time.Minute
+	}
+
+	if models.RunLookahead < 2*RunInterval {
+		models.RunLookahead = 2 * RunInterval
+	}
+
+	if err := app.Worker.Register("runs", runsJob); err != nil {
+		log.Println("Failed to register the runs job:", err)
+		return
+	}
+
+	// the first job runs as soon as the worker starts
+	if err := app.Worker.PerformIn(worker.Job{Handler: "runs"}, 0); err != nil {
+		log.Println("Failed to schedule the runs job:", err)
+	}
+}
+
+// runsJob creates the runs that open before the next job and queues the next one, even if this one failed
+func runsJob(worker.Args) error {
+	defer func() {
+		if err := app.Worker.PerformIn(worker.Job{Handler: "runs"}, RunInterval); err != nil {
+			log.Println("Failed to schedule the runs job:", err)
+		}
+	}()
+
+	return models.DB.Transaction(func(tx *pop.Connection) error {
+		if err := models.MaterializeAllRuns(tx, time.Now().Add(models.RunLookahead)); err != nil {
+			log.Println("Failed to create campaign runs:", err)
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,158 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// SectionsGet gets a section by ID.
+// GET /v1/tweaser/sections/{section_id}
+func SectionsGet(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	// Allocate an empty Section
+	section := &models.Section{}
+
+	// To find the Section the parameter section_id is used.
+	if err := tx.Eager("Questions").Find(section, c.Param("section_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	return c.Render(200, r.JSON(section))
+}
+
+// SectionsCreate creates a section.
+// POST /v1/tweaser/sections
+func SectionsCreate(c buffalo.Context) error {
+	// Allocate an empty Section
+	section := &models.Section{}
+
+	// bind the request body to the new section
+	if err := c.Bind(section); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	// Validate the posted data and save it to the database
+	verrs, err := tx.ValidateAndCreate(section)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return c.Render(422, r.JSON(verrs))
+	}
+
+	return c.Render(201, r.JSON(section))
+}
+
+// SectionsUpdate updates a section.
+// PUT /v1/tweaser/sections/{section_id}
+func SectionsUpdate(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	// Allocate an empty Section
+	section := &models.Section{}
+
+	if err := tx.Find(section, c.Param("section_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	// bind the request body to the section
+	if err := c.Bind(section); err != nil {
+		return errors.WithStack(err)
+	}
+
+	verrs, err := tx.ValidateAndUpdate(section)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return c.Render(422, r.JSON(verrs))
+	}
+
+	return c.Render(200, r.JSON(section))
+}
+
+// SectionsSubmit submits the responses to a page of questions as a unit.  The page's required questions
+// have to be answered, either in this submission or before it.
+// POST /v1/tweaser/sections/{section_id}/responses
+func SectionsSubmit(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	section := &models.Section{}
+	if err := tx.Find(section, c.Param("section_id")); err != nil {
+		return c.Render(404, r.JSON("Section Not Found."))
+	}
+
+	// only questions on this page can be submitted with it
+	onPage := func(question *models.Question) string {
+		if !question.SectionID.Valid || question.SectionID.UUID != section.ID {
+			return "Question is not in this section."
+		}
+		return ""
+	}
+
+	// and the page isn't done until its required questions are answered
+	answeredRequired := func(userID string) (map[string]*validate.Errors, error) {
+		runID, err := models.RunFor(tx, section.CampaignID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		campaign := &models.Campaign{}
+		if err := tx.Find(campaign, section.CampaignID); err != nil {
+			return nil, err
+		}
+
+		progress, err := models.CampaignProgress(tx, campaign.ID, campaign.RespondentID(userID), runID)
+		if err != nil {
+			return nil, err
+		}
+
+		remaining := map[uuid.UUID]bool{}
+		for _, id := range progress.Remaining {
+			remaining[id] = true
+		}
+
+		questions := models.Questions{}
+		if err := tx.Where("section_id = ?", section.ID).Where("enabled = true").Where("required = true").All(&questions); err != nil {
+			return nil, err
+		}
+
+		failed := map[string]*validate.Errors{}
+		for _, q := range questions {
+			if remaining[q.ID] {
+				failed[q.ID.String()] = validate.NewErrors()
+				failed[q.ID.String()].Add("required", "Question is required.")
+			}
+		}
+		return failed, nil
+	}
+
+	return submitBatch(c, tx, section.CampaignID, onPage, answeredRequired)
+}
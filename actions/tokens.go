@@ -0,0 +1,132 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/YaleSpinup/tweaser/helpers"
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// TokenTTL is how long a question token can be used after it is issued, defaults to a day
+var TokenTTL = envDuration("TOKEN_TTL")
+
+// newQuestionToken generates the token a user passes back to act on a question
+func newQuestionToken(userID string, questionID uuid.UUID) (string, error) {
+	ttl := TokenTTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	mt := helpers.ModelToken{
+		ID:        questionID,
+		Secret:    CryptToken,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+	return mt.Generate()
+}
+
+// newOptInToken generates the token a user who opted out of everything passes back to opt in again, since
+// they aren't served any questions to get question tokens for.  It is generated with its own secret, so it
+// can't be passed off as a question token.
+func newOptInToken(userID string) (string, error) {
+	ttl := TokenTTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	mt := helpers.ModelToken{
+		Secret:    CryptToken + ":opt-in",
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+	return mt.Generate()
+}
+
+// validateOptInToken validates that the token passed by a user is an opt in token generated for them, and
+// that it hasn't expired or been revoked since
+func validateOptInToken(tx *pop.Connection, token, userID string) error {
+	mt := &helpers.ModelToken{
+		Secret: CryptToken + ":opt-in",
+		UserID: userID,
+	}
+	if err := mt.Validate(token); err != nil {
+		return err
+	}
+
+	revoked, err := models.TokenRevoked(tx, userID, uuid.Nil, time.Unix(mt.IssuedAt, 0))
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		return errors.New("revoked token")
+	}
+	return nil
+}
+
+// validateQuestionToken validates that the token passed by a user was generated
+// for that user and question when the question list was built, and that it hasn't
+// expired or been revoked since
+func validateQuestionToken(tx *pop.Connection, token, userID string, questionID uuid.UUID) (*helpers.ModelToken, error) {
+	if token == "" {
+		return nil, errors.New("missing token")
+	}
+
+	mt := &helpers.ModelToken{
+		ID:     questionID,
+		Secret: CryptToken,
+		UserID: userID,
+	}
+	if err := mt.Validate(token); err != nil {
+		return nil, err
+	}
+
+	revoked, err := models.TokenRevoked(tx, userID, questionID, time.Unix(mt.IssuedAt, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, errors.New("revoked token")
+	}
+	return mt, nil
+}
+
+// TokensRevoke revokes the outstanding question tokens for a question, a user or a user's tokens for a
+// question.  Users get new tokens the next time their question list is built.
+// POST /v1/tweaser/admin/tokens/revoke
+func TokensRevoke(c buffalo.Context) error {
+	revocation := &models.TokenRevocation{}
+
+	// bind the request body to the new revocation
+	if err := c.Bind(revocation); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	revocation.RevokedAt = time.Time{}
+	verrs, err := tx.ValidateAndCreate(revocation)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return c.Render(422, r.JSON(verrs))
+	}
+
+	if err := models.RecordAudit(tx, models.AuditRevokeTokens, revocation); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(201, r.JSON(revocation))
+}
@@ -0,0 +1,157 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// UsersGetResponses gets all of a user's responses, with the question, campaign and answers spelled out.
+// GET /v1/tweaser/admin/users/{user_id}/responses
+func UsersGetResponses(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	history, err := models.ResponseHistory(tx, c.Param("user_id"))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(200, r.JSON(history))
+}
+
+// UsersGetOwnResponses lets a user see all of their responses.  It is authenticated with the token for any
+// question served to the user.  Responses that can still be edited or retracted are marked editable, and the
+// one named by edit comes with a new token for its question, since answered questions aren't served again
+// and the token that submitted it expires.  Only one token is issued per request, since generating them is
+// slow on purpose.
+// GET /v1/tweaser/users/{user_id}/responses?token=xxxxx&question_id=xxxxx&edit=xxxxx
+func UsersGetOwnResponses(c buffalo.Context) error {
+	token := c.Param("token")
+	if token == "" {
+		return c.Error(403, errors.New("Unauthorized."))
+	}
+
+	questionID, err := uuid.FromString(c.Param("question_id"))
+	if err != nil {
+		return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	userID := c.Param("user_id")
+	if err := limitByToken(c, userID, token); err != nil {
+		return err
+	}
+
+	if _, err := validateQuestionToken(tx, token, userID, questionID); err != nil {
+		return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+	}
+
+	history, err := models.ResponseHistory(tx, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for i, entry := range history {
+		response := &models.Response{QuestionID: entry.QuestionID, RunID: entry.RunID, CreatedAt: entry.CreatedAt}
+		deadline, err := models.EditDeadline(tx, response, ResponseEditWindow)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		history[i].Editable = time.Now().Before(deadline)
+		if !history[i].Editable || entry.ID.String() != c.Param("edit") {
+			continue
+		}
+
+		if history[i].Token, err = newQuestionToken(userID, entry.QuestionID); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return c.Render(200, r.JSON(history))
+}
+
+// UsersExport exports everything stored about a user, for data subject access requests.  Rows in anonymous
+// campaigns are only counted, their contents are only exported to the user with UsersGetOwnExport.
+// GET /v1/tweaser/admin/users/{user_id}/export
+func UsersExport(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	export, err := models.ExportUserData(tx, c.Param("user_id"), false)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(200, r.JSON(export))
+}
+
+// UsersGetOwnExport lets a user export everything stored about them, including their rows in anonymous
+// campaigns.  It is authenticated with the token for any question served to the user.
+// GET /v1/tweaser/users/{user_id}/export?token=xxxxx&question_id=xxxxx
+func UsersGetOwnExport(c buffalo.Context) error {
+	token := c.Param("token")
+	if token == "" {
+		return c.Error(403, errors.New("Unauthorized."))
+	}
+
+	questionID, err := uuid.FromString(c.Param("question_id"))
+	if err != nil {
+		return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	userID := c.Param("user_id")
+	if err := limitByToken(c, userID, token); err != nil {
+		return err
+	}
+
+	if _, err := validateQuestionToken(tx, token, userID, questionID); err != nil {
+		return c.Render(403, r.JSON("Unauthorized. Invalid Token."))
+	}
+
+	export, err := models.ExportUserData(tx, userID, true)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(200, r.JSON(export))
+}
+
+// UsersErase erases a user, for right to be forgotten requests, and returns a receipt of what was removed.
+// DELETE /v1/tweaser/admin/users/{user_id}
+func UsersErase(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	receipt, err := models.EraseUserData(tx, c.Param("user_id"))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(200, r.JSON(receipt))
+}
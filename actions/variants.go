@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+)
+
+// VariantsGet gets a question variant by ID.
+// GET /v1/tweaser/variants/{variant_id}
+func VariantsGet(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	// Allocate an empty QuestionVariant
+	variant := &models.QuestionVariant{}
+
+	// To find the QuestionVariant the parameter variant_id is used.
+	if err := tx.Find(variant, c.Param("variant_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	return c.Render(200, r.JSON(variant))
+}
+
+// VariantsCreate creates a question variant.
+// POST /v1/tweaser/variants
+func VariantsCreate(c buffalo.Context) error {
+	// Allocate an empty QuestionVariant
+	variant := &models.QuestionVariant{}
+
+	// bind the request body to the new variant
+	if err := c.Bind(variant); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	// Validate the posted data and save it to the database
+	verrs, err := tx.ValidateAndCreate(variant)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return c.Render(422, r.JSON(verrs))
+	}
+
+	return c.Render(201, r.JSON(variant))
+}
+
+// VariantsUpdate updates a question variant.
+// PUT /v1/tweaser/variants/{variant_id}
+func VariantsUpdate(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	// Allocate an empty QuestionVariant
+	variant := &models.QuestionVariant{}
+
+	if err := tx.Find(variant, c.Param("variant_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	// bind the request body to the variant
+	if err := c.Bind(variant); err != nil {
+		return errors.WithStack(err)
+	}
+
+	verrs, err := tx.ValidateAndUpdate(variant)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return c.Render(422, r.JSON(verrs))
+	}
+
+	return c.Render(200, r.JSON(variant))
+}
@@ -0,0 +1,41 @@
+// Package fixtures creates the records shared by the model and action test suites.  The TOML scenarios in
+// this directory are loaded by the suites themselves.
+package fixtures
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YaleSpinup/tweaser/models"
+)
+
+// Campaign creates an enabled campaign that is open from an hour ago until tomorrow
+func Campaign(t testing.TB, name string, priority int) *models.Campaign {
+	campaign := &models.Campaign{
+		Name:      name,
+		StartDate: time.Now().Add(-time.Hour),
+		EndDate:   time.Now().Add(24 * time.Hour),
+		Enabled:   true,
+		Priority:  priority,
+	}
+	if err := models.DB.Create(campaign); err != nil {
+		t.Fatalf("failed to create campaign %s: %s", name, err)
+	}
+	return campaign
+}
+
+// Question creates an enabled input question in the campaign
+func Question(t testing.TB, campaign *models.Campaign, text string, priority, position int) *models.Question {
+	question := &models.Question{
+		Text:       text,
+		CampaignID: campaign.ID,
+		Enabled:    true,
+		Type:       "input",
+		Priority:   priority,
+		Position:   position,
+	}
+	if err := models.DB.Create(question); err != nil {
+		t.Fatalf("failed to create question %s: %s", text, err)
+	}
+	return question
+}
@@ -3,13 +3,16 @@ module github.com/YaleSpinup/tweaser
 go 1.21
 
 require (
+	github.com/go-sql-driver/mysql v1.7.1
 	github.com/gobuffalo/buffalo v1.1.0
 	github.com/gobuffalo/buffalo-pop/v3 v3.0.7
 	github.com/gobuffalo/envy v1.10.2
 	github.com/gobuffalo/grift v1.5.2
+	github.com/gobuffalo/httptest v1.5.2
 	github.com/gobuffalo/mw-contenttype v1.0.2
 	github.com/gobuffalo/mw-forcessl v1.0.2
 	github.com/gobuffalo/mw-paramlogger v1.0.2
+	github.com/gobuffalo/nulls v0.4.2
 	github.com/gobuffalo/packr/v2 v2.8.3
 	github.com/gobuffalo/pop/v6 v6.1.1
 	github.com/gobuffalo/suite/v3 v3.0.2
@@ -33,17 +36,14 @@ require (
 	github.com/fatih/structs v1.1.0 // indirect
 	github.com/felixge/httpsnoop v1.0.4 // indirect
 	github.com/fsnotify/fsnotify v1.7.0 // indirect
-	github.com/go-sql-driver/mysql v1.7.1 // indirect
 	github.com/gobuffalo/events v1.4.3 // indirect
 	github.com/gobuffalo/fizz v1.14.4 // indirect
 	github.com/gobuffalo/flect v1.0.2 // indirect
 	github.com/gobuffalo/github_flavored_markdown v1.1.4 // indirect
 	github.com/gobuffalo/helpers v0.6.7 // indirect
-	github.com/gobuffalo/httptest v1.5.2 // indirect
 	github.com/gobuffalo/logger v1.0.7 // indirect
 	github.com/gobuffalo/meta v0.3.3 // indirect
 	github.com/gobuffalo/mw-csrf v1.0.0 // indirect
-	github.com/gobuffalo/nulls v0.4.2 // indirect
 	github.com/gobuffalo/packd v1.0.2 // indirect
 	github.com/gobuffalo/plush/v4 v4.1.19 // indirect
 	github.com/gobuffalo/pop/v5 v5.3.4 // indirect
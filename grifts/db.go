@@ -6,10 +6,13 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/YaleSpinup/tweaser/actions"
 	"github.com/YaleSpinup/tweaser/models"
 	"github.com/gobuffalo/grift/grift"
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
 )
 
 var _ = grift.Namespace("db", func() {
@@ -22,6 +25,34 @@ var _ = grift.Namespace("db", func() {
 
 		return err
 	})
+
+	_ = grift.Desc("purge", "Applies the data retention policies, printing what was removed: db:purge [dry-run]")
+	grift.Add("purge", func(c *grift.Context) error {
+		dryRun := len(c.Args) > 0 && (c.Args[0] == "dry-run" || c.Args[0] == "--dry-run")
+
+		return models.DB.Transaction(func(tx *pop.Connection) error {
+			results, err := models.Purge(tx, actions.DefaultRetention, time.Now(), dryRun)
+			if err != nil {
+				return err
+			}
+			return printJSON(results)
+		})
+	})
+
+	_ = grift.Desc("rekey", "Re-encrypts response text with the current ENCRYPTION_KEY_ID after a key rotation")
+	grift.Add("rekey", func(c *grift.Context) error {
+		if !models.TextKeys.Enabled() {
+			return errors.New("ENCRYPTION_KEYS isn't set")
+		}
+
+		return models.DB.Transaction(func(tx *pop.Connection) error {
+			result, err := models.RekeyText(tx, 500)
+			if err != nil {
+				return err
+			}
+			return printJSON(result)
+		})
+	})
 })
 
 func seedCampaigns(c *grift.Context) error {
@@ -177,10 +208,23 @@ func seedCampaigns(c *grift.Context) error {
 		return err
 	}
 
+	mcs1, err := newSection("Choices", mc.ID, 0)
+	if err != nil {
+		return err
+	}
+
+	mcs2, err := newSection("In your own words", mc.ID, 1)
+	if err != nil {
+		return err
+	}
+
 	mcq1, err := newQuestion("How do you feel about too many questions?", "single", mc.ID, true)
 	if err != nil {
 		return err
 	}
+	if err = moveToSection(mcq1, mcs1); err != nil {
+		return err
+	}
 	if _, err = newAnswer("Good", mcq1.ID, true); err != nil {
 		return err
 	}
@@ -195,6 +239,9 @@ func seedCampaigns(c *grift.Context) error {
 	if err != nil {
 		return err
 	}
+	if err = moveToSection(mcq2, mcs1); err != nil {
+		return err
+	}
 	if _, err = newAnswer("Good", mcq2.ID, true); err != nil {
 		return err
 	}
@@ -208,15 +255,21 @@ func seedCampaigns(c *grift.Context) error {
 		return err
 	}
 
-	_, err = newQuestion("How do you feel about disabled free form questions?", "input", mc.ID, false)
+	mcq3, err := newQuestion("How do you feel about disabled free form questions?", "input", mc.ID, false)
 	if err != nil {
 		return err
 	}
+	if err = moveToSection(mcq3, mcs2); err != nil {
+		return err
+	}
 
-	_, err = newQuestion("How do you feel about enabled free form questions?", "input", mc.ID, true)
+	mcq4, err := newQuestion("How do you feel about enabled free form questions?", "input", mc.ID, true)
 	if err != nil {
 		return err
 	}
+	if err = moveToSection(mcq4, mcs2); err != nil {
+		return err
+	}
 
 	log.Println("Seeded Campaigns")
 	return err
@@ -251,6 +304,32 @@ func newQuestion(text, qType string, campaignID uuid.UUID, enabled bool) (*model
 	return &question, nil
 }
 
+func newSection(title string, campaignID uuid.UUID, position int) (*models.Section, error) {
+	tx, err := pop.Connect("development")
+	if err != nil {
+		return nil, err
+	}
+
+	section := models.Section{Title: title, CampaignID: campaignID, Position: position}
+	_, err = tx.ValidateAndSave(&section)
+	if err != nil {
+		return nil, err
+	}
+
+	return &section, nil
+}
+
+func moveToSection(question *models.Question, section *models.Section) error {
+	tx, err := pop.Connect("development")
+	if err != nil {
+		return err
+	}
+
+	question.SectionID = nulls.NewUUID(section.ID)
+	_, err = tx.ValidateAndSave(question)
+	return err
+}
+
 func newAnswer(text string, questionID uuid.UUID, enabled bool) (*models.Answer, error) {
 	tx, err := pop.Connect("development")
 	if err != nil {
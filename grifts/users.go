@@ -0,0 +1,54 @@
+package grifts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/grift/grift"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+)
+
+var _ = grift.Namespace("users", func() {
+	_ = grift.Desc("export", "Exports everything stored about a user as JSON: users:export <user_id>")
+	grift.Add("export", func(c *grift.Context) error {
+		if len(c.Args) != 1 {
+			return errors.New("usage: users:export <user_id>")
+		}
+
+		return models.DB.Transaction(func(tx *pop.Connection) error {
+			export, err := models.ExportUserData(tx, c.Args[0], false)
+			if err != nil {
+				return err
+			}
+			return printJSON(export)
+		})
+	})
+
+	_ = grift.Desc("erase", "Erases a user and prints a receipt of what was removed: users:erase <user_id>")
+	grift.Add("erase", func(c *grift.Context) error {
+		if len(c.Args) != 1 {
+			return errors.New("usage: users:erase <user_id>")
+		}
+
+		return models.DB.Transaction(func(tx *pop.Connection) error {
+			receipt, err := models.EraseUserData(tx, c.Args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(receipt)
+		})
+	})
+})
+
+func printJSON(v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(os.Stdout, string(out))
+	return err
+}
@@ -0,0 +1,183 @@
+package helpers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// envelopePrefix marks a value encrypted by a Keyring, anything without it is plain text
+const envelopePrefix = "enc:"
+
+// Keyring holds the master keys used for envelope encryption.  Each value is encrypted with its own random
+// data key, and the data key is wrapped with the current master key.  The ID of the master key is stored
+// with the value so older keys can still decrypt it after a rotation.
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyring parses a comma separated list of id:key pairs, where each key is 32 bytes encoded in base64.
+// New values are encrypted with the key named by current, or the first key listed if current is empty.
+// An empty list gives a keyring that leaves values in plain text.
+func NewKeyring(spec, current string) (*Keyring, error) {
+	k := &Keyring{keys: map[string][]byte{}}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("key %q must be given as id:key", pair)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("key %s must be 32 bytes encoded in base64", parts[0])
+		}
+
+		if _, ok := k.keys[parts[0]]; ok {
+			return nil, fmt.Errorf("key %s is listed more than once", parts[0])
+		}
+		k.keys[parts[0]] = key
+
+		if k.current == "" {
+			k.current = parts[0]
+		}
+	}
+
+	if current != "" {
+		if _, ok := k.keys[current]; !ok {
+			return nil, fmt.Errorf("current key %s isn't in the list of keys", current)
+		}
+		k.current = current
+	}
+
+	return k, nil
+}
+
+// Enabled reports whether the keyring has a key to encrypt with
+func (k *Keyring) Enabled() bool {
+	return k.current != ""
+}
+
+// Current is the ID of the key new values are encrypted with
+func (k *Keyring) Current() string {
+	return k.current
+}
+
+// KeyID is the ID of the key a value was encrypted with, or empty for plain text
+func (k *Keyring) KeyID(value string) string {
+	if !strings.HasPrefix(value, envelopePrefix) {
+		return ""
+	}
+	return strings.SplitN(strings.TrimPrefix(value, envelopePrefix), ":", 2)[0]
+}
+
+// Encrypt encrypts a value with a new data key wrapped by the current master key.  Empty values and
+// values given to a keyring without keys are returned unchanged.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" || !k.Enabled() {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	wrapped, err := seal(k.keys[k.current], dataKey, []byte(k.current))
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(dataKey, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return envelopePrefix + k.current + ":" + base64.StdEncoding.EncodeToString(wrapped) + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt unwraps the data key with the master key the value names and decrypts the value.  Plain text,
+// and any value given to a keyring without keys, is returned unchanged.  Callers should keep track of
+// which values they encrypted, since plain text can start with the same prefix.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !k.Enabled() || !strings.HasPrefix(value, envelopePrefix) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	key, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("unknown encryption key %s", parts[0])
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := open(key, wrapped, []byte(parts[0]))
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key with key %s: %s", parts[0], err)
+	}
+
+	plaintext, err := open(dataKey, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %s", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// seal encrypts with AES-GCM, prepending the random nonce
+func seal(key, plaintext, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+// open decrypts a value sealed by seal
+func open(key, sealed, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], additional)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
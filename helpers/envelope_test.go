@@ -0,0 +1,111 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+var (
+	testKey1 = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("1", 32)))
+	testKey2 = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("2", 32)))
+)
+
+func TestKeyringRoundTrip(t *testing.T) {
+	k, err := NewKeyring("k1:"+testKey1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := k.Encrypt("my phone number is 555-1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(enc, "555-1234") || k.KeyID(enc) != "k1" {
+		t.Errorf("expected a value encrypted with k1, got %s", enc)
+	}
+
+	again, _ := k.Encrypt("my phone number is 555-1234")
+	if again == enc {
+		t.Error("expected each value to get its own data key")
+	}
+
+	dec, err := k.Decrypt(enc)
+	if err != nil || dec != "my phone number is 555-1234" {
+		t.Errorf("expected the original text, got %q (%v)", dec, err)
+	}
+
+	if enc, _ := k.Encrypt(""); enc != "" {
+		t.Errorf("expected empty text to stay empty, got %s", enc)
+	}
+
+	if dec, err := k.Decrypt("plain old text"); err != nil || dec != "plain old text" {
+		t.Errorf("expected plain text to be returned as is, got %q (%v)", dec, err)
+	}
+
+	// without keys nothing was encrypted, so text that looks encrypted is still just text
+	disabled, _ := NewKeyring("", "")
+	if dec, err := disabled.Decrypt("enc:a:b:c"); err != nil || dec != "enc:a:b:c" {
+		t.Errorf("expected a keyring without keys to return values as is, got %q (%v)", dec, err)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old, _ := NewKeyring("k1:"+testKey1, "")
+	enc, _ := old.Encrypt("secret")
+
+	k, err := NewKeyring("k1:"+testKey1+",k2:"+testKey2, "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dec, err := k.Decrypt(enc); err != nil || dec != "secret" {
+		t.Errorf("expected the old key to still decrypt, got %q (%v)", dec, err)
+	}
+
+	if enc, _ := k.Encrypt("secret"); k.KeyID(enc) != "k2" {
+		t.Errorf("expected new values to use the current key, got %s", enc)
+	}
+
+	dropped, _ := NewKeyring("k2:"+testKey2, "")
+	if _, err := dropped.Decrypt(enc); err == nil {
+		t.Error("expected an error for a value encrypted with a key that was removed")
+	}
+
+	// a data key wrapped by one master key can't be passed off as wrapped by another
+	swapped := "enc:k2:" + strings.TrimPrefix(enc, "enc:k1:")
+	if _, err := k.Decrypt(swapped); err == nil {
+		t.Error("expected an error for a value with the wrong key ID")
+	}
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		spec    string
+		current string
+		enabled bool
+		err     bool
+	}{
+		{spec: "", enabled: false},
+		{spec: "k1:" + testKey1 + ", k2:" + testKey2, current: "k2", enabled: true},
+		{spec: "k1:" + testKey1, current: "k2", err: true},
+		{spec: "k1:c2hvcnQ=", err: true},
+		{spec: testKey1, err: true},
+		{spec: "k1:" + testKey1 + ",k1:" + testKey2, err: true},
+	}
+
+	for _, test := range tests {
+		k, err := NewKeyring(test.spec, test.current)
+		if test.err {
+			if err == nil {
+				t.Errorf("expected an error for %q", test.spec)
+			}
+			continue
+		}
+
+		if err != nil || k.Enabled() != test.enabled {
+			t.Errorf("unexpected keyring for %q: %v", test.spec, err)
+		}
+	}
+}
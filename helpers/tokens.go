@@ -1,8 +1,15 @@
 package helpers
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
 	log "github.com/sirupsen/logrus"
@@ -10,46 +17,137 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-// ModelToken is the object used to generate a token loosesly associated with a model
+// ModelToken is the object used to generate a token loosesly associated with a model.  The token carries
+// its ID, when it was issued and when it expires, so it can be used once and revoked.
 type ModelToken struct {
-	UserID string    `json:"user_id"`
-	ID     uuid.UUID `json:"id"`
-	Secret string    `json:"secret"`
+	UserID    string    `json:"user_id"`
+	ID        uuid.UUID `json:"id"`
+	Secret    string    `json:"secret"`
+	JTI       uuid.UUID `json:"jti"`
+	IssuedAt  int64     `json:"iat"`
+	ExpiresAt int64     `json:"exp"`
 }
 
-// Generate creates a base64 encoded token
-func (r *ModelToken) Generate() (string, error) {
+// digest is the hash of the token's fields that bcrypt is run on, since bcrypt only reads 72 bytes
+func (r *ModelToken) digest() ([]byte, error) {
 	str, err := json.Marshal(r)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	log.Debugf("Marshalled secret JSON string %s", str)
 
-	token, err := bcrypt.GenerateFromPassword(str, bcrypt.DefaultCost)
+	sum := sha256.Sum256(str)
+	return []byte(hex.EncodeToString(sum[:])), nil
+}
+
+// Generate creates a token as jti.iat.exp.hash, with a base64 encoded hash.  A token ID and issue time are
+// filled in when they aren't set.  A token without an expiry never expires.
+func (r *ModelToken) Generate() (string, error) {
+	if r.JTI == uuid.Nil {
+		jti, err := uuid.NewV4()
+		if err != nil {
+			return "", err
+		}
+		r.JTI = jti
+	}
+
+	if r.IssuedAt == 0 {
+		r.IssuedAt = time.Now().Unix()
+	}
+
+	digest, err := r.digest()
+	if err != nil {
+		return "", err
+	}
+
+	token, err := bcrypt.GenerateFromPassword(digest, bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
 	}
 	log.Debugln("Secret hash:", string(token))
 
-	return base64.StdEncoding.EncodeToString(token), nil
+	return fmt.Sprintf("%s.%d.%d.%s", r.JTI, r.IssuedAt, r.ExpiresAt, base64.StdEncoding.EncodeToString(token)), nil
 }
 
-// Validate validates a base64 encoded token
-func (r *ModelToken) Validate(token string) error {
-	decodedToken, err := base64.StdEncoding.DecodeString(token)
+// maxTokenLength is the longest token, a UUID, two timestamps and a base64 encoded bcrypt hash
+const maxTokenLength = 160
+
+var (
+	// ErrMalformedToken is returned for tokens that couldn't have been generated by ModelToken
+	ErrMalformedToken = errors.New("malformed token")
+
+	// ErrExpiredToken is returned for tokens past their expiry
+	ErrExpiredToken = errors.New("expired token")
+)
+
+// CheckTokenFormat runs the checks Validate makes before comparing, so callers can reject a token that
+// couldn't have been generated by ModelToken, or has expired, without the slow comparison
+func CheckTokenFormat(token string) error {
+	_, _, _, _, err := parseToken(token)
+	return err
+}
+
+// parseToken splits a token into its ID, issue time, expiry and decoded hash, rejecting tokens that are
+// malformed, expired or aren't a bcrypt hash at the cost they are generated with
+func parseToken(token string) (uuid.UUID, int64, int64, []byte, error) {
+	if len(token) > maxTokenLength {
+		return uuid.Nil, 0, 0, nil, ErrMalformedToken
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return uuid.Nil, 0, 0, nil, ErrMalformedToken
+	}
+
+	jti, err := uuid.FromString(parts[0])
+	if err != nil {
+		return uuid.Nil, 0, 0, nil, ErrMalformedToken
+	}
+
+	iat, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return uuid.Nil, 0, 0, nil, ErrMalformedToken
+	}
+
+	exp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return uuid.Nil, 0, 0, nil, ErrMalformedToken
+	}
+
+	if exp != 0 && time.Now().Unix() >= exp {
+		return uuid.Nil, 0, 0, nil, ErrExpiredToken
+	}
+
+	decodedToken, err := base64.StdEncoding.DecodeString(parts[3])
 	if err != nil {
 		log.Error("Failed to decode base64", err)
+		return uuid.Nil, 0, 0, nil, ErrMalformedToken
+	}
+
+	if cost, err := bcrypt.Cost(decodedToken); err != nil || cost != bcrypt.DefaultCost {
+		return uuid.Nil, 0, 0, nil, ErrMalformedToken
+	}
+
+	return jti, iat, exp, decodedToken, nil
+}
+
+// Validate validates a token, filling in its ID, issue time and expiry.  Tokens that fail
+// CheckTokenFormat are rejected before comparing, since the comparison is slow on purpose.
+func (r *ModelToken) Validate(token string) error {
+	jti, iat, exp, decodedToken, err := parseToken(token)
+	if err != nil {
 		return err
 	}
 
-	str, err := json.Marshal(r)
+	r.JTI, r.IssuedAt, r.ExpiresAt = jti, iat, exp
+	digest, err := r.digest()
 	if err != nil {
 		log.Error("Failed to marshall JSON", err)
 		return err
 	}
 
-	log.Debugf("Comparing decodedToken: %s with secret %s", decodedToken, string(str))
+	log.Debugf("Comparing decodedToken: %s with digest %s", decodedToken, digest)
 
-	return bcrypt.CompareHashAndPassword(decodedToken, str)
+	return bcrypt.CompareHashAndPassword(decodedToken, digest)
 }
@@ -0,0 +1,92 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestModelToken(t *testing.T) {
+	mt := ModelToken{
+		UserID:    "someone.with.a.rather.long.user.id@example.edu",
+		ID:        uuid.Must(uuid.NewV4()),
+		Secret:    "yyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyy",
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}
+
+	token, err := mt.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := ModelToken{UserID: mt.UserID, ID: mt.ID, Secret: mt.Secret}
+	if err := check.Validate(token); err != nil {
+		t.Fatalf("expected the token to validate, got %s", err)
+	}
+
+	if check.JTI != mt.JTI || check.IssuedAt != mt.IssuedAt || check.ExpiresAt != mt.ExpiresAt {
+		t.Errorf("expected the token's ID and times to be filled in, got %+v", check)
+	}
+
+	other := ModelToken{UserID: "someotherguy", ID: mt.ID, Secret: mt.Secret}
+	if err := other.Validate(token); err == nil {
+		t.Error("expected the token not to validate for another user")
+	}
+
+	// the ID and times can't be changed without invalidating the token
+	parts := strings.Split(token, ".")
+	tampered := fmt.Sprintf("%s.%s.%d.%s", parts[0], parts[1], time.Now().Add(48*time.Hour).Unix(), parts[3])
+	if err := check.Validate(tampered); err == nil {
+		t.Error("expected a token with a changed expiry not to validate")
+	}
+}
+
+func TestModelTokenExpired(t *testing.T) {
+	mt := ModelToken{UserID: "someguy", ID: uuid.Must(uuid.NewV4()), Secret: "yyyyyyyyyyyyyyyyyyyy", ExpiresAt: time.Now().Add(-time.Minute).Unix()}
+	token, err := mt.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := ModelToken{UserID: mt.UserID, ID: mt.ID, Secret: mt.Secret}
+	if err := check.Validate(token); err != ErrExpiredToken {
+		t.Errorf("expected an expired token error, got %v", err)
+	}
+}
+
+func TestModelTokenMalformed(t *testing.T) {
+	mt := ModelToken{UserID: "someguy", ID: uuid.Must(uuid.NewV4()), Secret: "yyyyyyyyyyyyyyyyyyyy"}
+	prefix := uuid.Must(uuid.NewV4()).String() + ".1700000000.0."
+
+	// a hash at a much higher cost would take minutes to compare
+	costly := "$2a$31$" + strings.Repeat("a", 53)
+
+	for _, token := range []string{
+		strings.Repeat("A", 4096),
+		base64.StdEncoding.EncodeToString([]byte("not a token")),
+		"not-a-uuid.1700000000.0.aGFzaA==",
+		prefix + base64.StdEncoding.EncodeToString([]byte("not a hash")),
+		prefix + base64.StdEncoding.EncodeToString([]byte(costly)),
+	} {
+		if err := mt.Validate(token); err != ErrMalformedToken {
+			t.Errorf("expected a malformed token error for %.40s, got %v", token, err)
+		}
+
+		if err := CheckTokenFormat(token); err != ErrMalformedToken {
+			t.Errorf("expected the format check to reject %.40s, got %v", token, err)
+		}
+	}
+
+	token, err := mt.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckTokenFormat(token); err != nil {
+		t.Errorf("expected a generated token to pass the format check, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package helpers
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// ParseUserIDs reads user IDs from CSV or newline delimited text.  The user ID is taken from the
+// first column of each row.  Blank rows, a user_id header and duplicate IDs are skipped.
+func ParseUserIDs(r io.Reader) ([]string, error) {
+	rows, err := ParseRows(r)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, row := range rows {
+		if !seen[row[0]] {
+			seen[row[0]] = true
+			ids = append(ids, row[0])
+		}
+	}
+	return ids, nil
+}
+
+// ParseRows reads rows starting with a user ID from CSV or newline delimited text.  Fields are trimmed,
+// and blank rows, duplicate rows and a header row starting with user_id are skipped.
+func ParseRows(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	seen := map[string]bool{}
+	rows := [][]string{}
+	for line := 0; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+
+		key := strings.Join(record, "\x00")
+		if record[0] == "" || seen[key] || (line == 0 && strings.EqualFold(record[0], "user_id")) {
+			continue
+		}
+
+		seen[key] = true
+		rows = append(rows, record)
+	}
+
+	return rows, nil
+}
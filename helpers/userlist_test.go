@@ -0,0 +1,78 @@
+package helpers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseUserIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "newline delimited",
+			input:    "someguy\nsomegal\n\nsomeone\n",
+			expected: []string{"someguy", "somegal", "someone"},
+		},
+		{
+			name:     "windows line endings",
+			input:    "someguy\r\nsomegal\r\n",
+			expected: []string{"someguy", "somegal"},
+		},
+		{
+			name:     "csv with header and extra columns",
+			input:    "user_id,name\nsomeguy,Some Guy\n somegal , Some Gal\n",
+			expected: []string{"someguy", "somegal"},
+		},
+		{
+			name:     "duplicates and comments",
+			input:    "# beta testers\nsomeguy\nsomeguy\nsomegal\n",
+			expected: []string{"someguy", "somegal"},
+		},
+		{
+			name:     "empty",
+			input:    "",
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUserIDs(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseRows(t *testing.T) {
+	input := "user_id,category\nsomeguy\nsomeguy,product research\nsomeguy,product research\nsomegal,service satisfaction\n"
+	expected := [][]string{
+		{"someguy"},
+		{"someguy", "product research"},
+		{"somegal", "service satisfaction"},
+	}
+
+	got, err := ParseRows(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestParseUserIDsBadCSV(t *testing.T) {
+	if _, err := ParseUserIDs(strings.NewReader("someguy,\"unterminated\n")); err == nil {
+		t.Error("expected an error for a bad csv")
+	}
+}
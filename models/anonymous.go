@@ -0,0 +1,122 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// NotAnonymousSQL is a where clause fragment on responses that leaves out responses to anonymous campaigns
+const NotAnonymousSQL = "question_id NOT IN (SELECT questions.id FROM questions JOIN campaigns ON campaigns.id = questions.campaign_id WHERE campaigns.anonymous = true)"
+
+// AnonymizationKey keys the hashes stored in place of user IDs for anonymous campaigns.  Without a key the
+// hashes could be reversed by hashing every user ID, so the app refuses to start when it is empty.
+var AnonymizationKey = envy.Get("ANONYMIZATION_KEY", envy.Get("CRYPT_TOKEN", ""))
+
+// RespondentID returns the ID to store with a user's responses to the campaign.  For anonymous campaigns it
+// is a keyed hash of the user ID, salted with the campaign so a user's responses to different campaigns
+// can't be linked.  Otherwise it is the user ID.
+func (c *Campaign) RespondentID(userID string) string {
+	if !c.Anonymous {
+		return userID
+	}
+
+	mac := hmac.New(sha256.New, []byte(AnonymizationKey))
+	mac.Write([]byte(c.ID.String() + ":" + userID))
+	return "anon-" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SuppressionID returns the keyed hash an erased user's opt outs, deny list entries and token revocations are
+// kept under, so they still apply if the user comes back but don't name them
+func SuppressionID(userID string) string {
+	mac := hmac.New(sha256.New, []byte(AnonymizationKey))
+	mac.Write([]byte("suppression:" + userID))
+	return "suppressed-" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CampaignForQuestion loads the campaign a question belongs to
+func CampaignForQuestion(tx *pop.Connection, questionID uuid.UUID) (*Campaign, error) {
+	campaign := &Campaign{}
+	if err := tx.RawQuery("SELECT * FROM campaigns WHERE id = (SELECT campaign_id FROM questions WHERE id = ?)", questionID).First(campaign); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// SuppressSmallCounts hides the counts that are small enough to identify the users behind them.  Counts
+// from 1 up to min are left out of the returned counts and their keys are returned as suppressed.  When
+// only one count would be hidden, the next smallest non-zero count is hidden too so the hidden count can't
+// be worked out from a total.
+func SuppressSmallCounts(counts map[string]int, min int) (map[string]int, []string) {
+	kept := map[string]int{}
+	suppressed := []string{}
+	for k, n := range counts {
+		if n > 0 && n < min {
+			suppressed = append(suppressed, k)
+			continue
+		}
+		kept[k] = n
+	}
+
+	if len(suppressed) == 1 {
+		next := ""
+		for k, n := range kept {
+			if n > 0 && (next == "" || n < kept[next] || (n == kept[next] && k < next)) {
+				next = k
+			}
+		}
+
+		if next != "" {
+			delete(kept, next)
+			suppressed = append(suppressed, next)
+		}
+	}
+
+	sort.Strings(suppressed)
+	return kept, suppressed
+}
+
+// SuppressSmallFields applies SuppressSmallCounts to a group of counts kept in separate fields.  Suppressed
+// fields are set to zero and their names returned.
+func SuppressSmallFields(fields map[string]*int, min int) []string {
+	counts := map[string]int{}
+	for name, n := range fields {
+		counts[name] = *n
+	}
+
+	kept, suppressed := SuppressSmallCounts(counts, min)
+	for name, n := range fields {
+		*n = kept[name]
+	}
+	return suppressed
+}
+
+// AnonymityUnchanged is a custom validator that keeps a campaign from switching between anonymous and
+// identified once it has responses, since its responses would no longer line up with its users
+type AnonymityUnchanged struct {
+	Name       string
+	CampaignID uuid.UUID
+	Anonymous  bool
+	tx         *pop.Connection
+}
+
+// IsValid validates that the campaign's anonymity hasn't changed since it got its first response
+func (v *AnonymityUnchanged) IsValid(errors *validate.Errors) {
+	stored := Campaign{}
+	if err := v.tx.Find(&stored, v.CampaignID); err != nil || stored.Anonymous == v.Anonymous {
+		return
+	}
+
+	var count int
+	if err := v.tx.Store.Get(&count, "SELECT COUNT(*) FROM responses WHERE question_id IN (SELECT id FROM questions WHERE campaign_id = ?)", v.CampaignID); err != nil || count > 0 {
+		errors.Add(validators.GenerateKey(v.Name), fmt.Sprintf("%s can't be changed once the campaign has responses.", v.Name))
+	}
+}
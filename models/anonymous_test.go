@@ -0,0 +1,84 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func Test_RespondentID(t *testing.T) {
+	c1 := Campaign{ID: uuid.Must(uuid.NewV4())}
+	if id := c1.RespondentID("someguy"); id != "someguy" {
+		t.Errorf("expected the user ID for an identified campaign, got %s", id)
+	}
+
+	c1.Anonymous = true
+	c2 := Campaign{ID: uuid.Must(uuid.NewV4()), Anonymous: true}
+
+	id := c1.RespondentID("someguy")
+	if id == "someguy" || id != c1.RespondentID("someguy") {
+		t.Errorf("expected a stable hash, got %s", id)
+	}
+
+	if id == c1.RespondentID("someotherguy") || id == c2.RespondentID("someguy") {
+		t.Error("expected different hashes for different users and campaigns")
+	}
+}
+
+func Test_SuppressSmallCounts(t *testing.T) {
+	tests := []struct {
+		counts     map[string]int
+		kept       map[string]int
+		suppressed []string
+	}{
+		{
+			counts:     map[string]int{"a": 10, "b": 7, "c": 0},
+			kept:       map[string]int{"a": 10, "b": 7, "c": 0},
+			suppressed: []string{},
+		},
+		{
+			counts:     map[string]int{"a": 10, "b": 2, "c": 3},
+			kept:       map[string]int{"a": 10},
+			suppressed: []string{"b", "c"},
+		},
+		// a single small count takes the next smallest with it
+		{
+			counts:     map[string]int{"a": 10, "b": 7, "c": 1, "d": 0},
+			kept:       map[string]int{"a": 10, "d": 0},
+			suppressed: []string{"b", "c"},
+		},
+		{
+			counts:     map[string]int{"a": 4},
+			kept:       map[string]int{},
+			suppressed: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		kept, suppressed := SuppressSmallCounts(tt.counts, 5)
+		if !reflect.DeepEqual(kept, tt.kept) || !reflect.DeepEqual(suppressed, tt.suppressed) {
+			t.Errorf("for %v expected %v and %v, got %v and %v", tt.counts, tt.kept, tt.suppressed, kept, suppressed)
+		}
+	}
+}
+
+func Test_SuppressSmallFields(t *testing.T) {
+	stats := CompletionStats{Respondents: 40, Started: 12, Completed: 3, CompletionRate: 0.25}
+	stats.Suppress(5)
+
+	// the small completed count takes the next smallest, started, with it and hides the rate
+	if stats.Respondents != 40 || stats.Started != 0 || stats.Completed != 0 || stats.CompletionRate != 0 {
+		t.Errorf("expected started, completed and the rate to be hidden, got %+v", stats)
+	}
+
+	if !reflect.DeepEqual(stats.Suppressed, []string{"completed", "started"}) {
+		t.Errorf("expected completed and started to be suppressed, got %v", stats.Suppressed)
+	}
+
+	run := RunStats{Respondents: 2, Questions: map[string]int{"q": 2}, Count: map[string]int{"a": 8, "b": 9}}
+	run.Suppress(5)
+	if run.Respondents != 0 || len(run.Questions) != 0 || !reflect.DeepEqual(run.Suppressed, []string{"respondents", "q"}) {
+		t.Errorf("expected the run's respondents and question count to be hidden, got %+v", run)
+	}
+}
@@ -4,19 +4,21 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gofrs/uuid"
 )
 
 type Answer struct {
-	ID         uuid.UUID `json:"id" db:"id"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
-	Text       string    `json:"text" db:"text"`
-	Enabled    bool      `json:"enabled" db:"enabled"`
-	Question   Question  `belongs_to:"question" json:"-"`
-	QuestionID uuid.UUID `json:"question_id" db:"question_id"`
+	ID           uuid.UUID `json:"id" db:"id"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	Text         string    `json:"text" db:"text"`
+	Enabled      bool      `json:"enabled" db:"enabled"`
+	MaxResponses nulls.Int `json:"max_responses" db:"max_responses"`
+	Question     Question  `belongs_to:"question" json:"-"`
+	QuestionID   uuid.UUID `json:"question_id" db:"question_id"`
 }
 
 // String is not required by pop and may be deleted
@@ -37,7 +39,9 @@ func (a Answers) String() string {
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
 // This method is not required and may be deleted.
 func (a *Answer) Validate(tx *pop.Connection) (*validate.Errors, error) {
-	return validate.NewErrors(), nil
+	return validate.Validate(
+		&QuotaIsPositive{Name: "MaxResponses", Field: a.MaxResponses},
+	), nil
 }
 
 // ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
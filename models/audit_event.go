@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// AuditPurge marks an event recording data removed by a retention policy
+	AuditPurge = "purge"
+
+	// AuditRevokeTokens marks an event recording question tokens revoked by an admin
+	AuditRevokeTokens = "revoke_tokens"
+)
+
+// AuditEvent records something done to the stored data outside of the normal flow of responses.  The
+// detail is JSON describing what was done.
+type AuditEvent struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Action    string    `json:"action" db:"action"`
+	Detail    string    `json:"detail" db:"detail"`
+}
+
+// String is not required by pop and may be deleted
+func (a AuditEvent) String() string {
+	ja, _ := json.Marshal(a)
+	return string(ja)
+}
+
+// AuditEvents is not required by pop and may be deleted
+type AuditEvents []AuditEvent
+
+// String is not required by pop and may be deleted
+func (a AuditEvents) String() string {
+	ja, _ := json.Marshal(a)
+	return string(ja)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (a *AuditEvent) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringInclusion{Field: a.Action, List: []string{AuditPurge, AuditRevokeTokens}, Name: "Action"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (a *AuditEvent) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (a *AuditEvent) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// RecordAudit adds an event to the audit trail with the detail marshalled to JSON
+func RecordAudit(tx *pop.Connection, action string, detail interface{}) error {
+	jd, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&AuditEvent{Action: action, Detail: string(jd)})
+}
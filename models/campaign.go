@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/YaleSpinup/tweaser/targeting"
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
@@ -11,14 +13,32 @@ import (
 )
 
 type Campaign struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
-	Name      string    `json:"name" db:"name"`
-	StartDate time.Time `json:"start_date" db:"start_date"`
-	EndDate   time.Time `json:"end_date" db:"end_date"`
-	Enabled   bool      `json:"enabled" db:"enabled"`
-	Questions Questions `has_many:"questions" json:"questions,omitempty"`
+	ID                    uuid.UUID    `json:"id" db:"id"`
+	CreatedAt             time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time    `json:"updated_at" db:"updated_at"`
+	Name                  string       `json:"name" db:"name"`
+	StartDate             time.Time    `json:"start_date" db:"start_date"`
+	EndDate               time.Time    `json:"end_date" db:"end_date"`
+	TimeZone              string       `json:"time_zone" db:"time_zone"`
+	Enabled               bool         `json:"enabled" db:"enabled"`
+	MaxResponses          nulls.Int    `json:"max_responses" db:"max_responses"`
+	ClosedAt              nulls.Time   `json:"closed_at" db:"closed_at"`
+	MaxPerDay             nulls.Int    `json:"max_per_day" db:"max_per_day"`
+	MaxPerWeek            nulls.Int    `json:"max_per_week" db:"max_per_week"`
+	CooldownSeconds       nulls.Int    `json:"cooldown_seconds" db:"cooldown_seconds"`
+	Priority              int          `json:"priority" db:"priority"`
+	Targeting             nulls.String `json:"targeting" db:"targeting"`
+	SamplePercent         nulls.Int    `json:"sample_percent" db:"sample_percent"`
+	Category              nulls.String `json:"category" db:"category"`
+	Anonymous             bool         `json:"anonymous" db:"anonymous"`
+	Recurrence            nulls.String `json:"recurrence" db:"recurrence"`
+	RunDurationSeconds    nulls.Int    `json:"run_duration_seconds" db:"run_duration_seconds"`
+	TextRetentionDays     nulls.Int    `json:"text_retention_days" db:"text_retention_days"`
+	ResponseRetentionDays nulls.Int    `json:"response_retention_days" db:"response_retention_days"`
+	RedactPII             bool         `json:"redact_pii" db:"redact_pii"`
+	RedactionPatterns     nulls.String `json:"redaction_patterns" db:"redaction_patterns"`
+	DropOriginalText      bool         `json:"drop_original_text" db:"drop_original_text"`
+	Questions             Questions    `has_many:"questions" order_by:"position asc" json:"questions,omitempty"`
 }
 
 // String is not required by pop and may be deleted
@@ -44,6 +64,17 @@ func (c *Campaign) Validate(tx *pop.Connection) (*validate.Errors, error) {
 		&validators.TimeIsPresent{Field: c.StartDate, Name: "StartDate"},
 		&validators.TimeIsPresent{Field: c.EndDate, Name: "EndDate"},
 		&validators.TimeIsBeforeTime{FirstName: "StartDate", FirstTime: c.StartDate, SecondName: "EndTime", SecondTime: c.EndDate},
+		&TimeZoneIsValid{Name: "TimeZone", Field: c.TimeZone},
+		&QuotaIsPositive{Name: "MaxResponses", Field: c.MaxResponses},
+		&QuotaIsPositive{Name: "MaxPerDay", Field: c.MaxPerDay},
+		&QuotaIsPositive{Name: "MaxPerWeek", Field: c.MaxPerWeek},
+		&QuotaIsPositive{Name: "CooldownSeconds", Field: c.CooldownSeconds},
+		&TargetingRuleCompiles{Name: "Targeting", Rule: c.Targeting.String},
+		&SamplePercentInRange{Name: "SamplePercent", Field: c.SamplePercent},
+		&RecurrenceIsValid{Name: "Recurrence", Rule: c.Recurrence, Duration: c.RunDurationSeconds},
+		&QuotaIsPositive{Name: "TextRetentionDays", Field: c.TextRetentionDays},
+		&QuotaIsPositive{Name: "ResponseRetentionDays", Field: c.ResponseRetentionDays},
+		&RedactionPatternsCompile{Name: "RedactionPatterns", Patterns: c.RedactionPatterns},
 	), nil
 }
 
@@ -56,5 +87,29 @@ func (c *Campaign) ValidateCreate(tx *pop.Connection) (*validate.Errors, error)
 // ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
 // This method is not required and may be deleted.
 func (c *Campaign) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
-	return validate.NewErrors(), nil
+	return validate.Validate(
+		&AnonymityUnchanged{Name: "Anonymous", CampaignID: c.ID, Anonymous: c.Anonymous, tx: tx},
+	), nil
+}
+
+// Targets returns true if the campaign's targeting rule matches the user attributes
+func (c *Campaign) Targets(attrs targeting.Attributes) (bool, error) {
+	rule, err := targeting.CompileCached(c.Targeting.String)
+	if err != nil {
+		return false, err
+	}
+	return rule.Match(attrs), nil
+}
+
+// TargetingRuleCompiles is a custom validator for campaign targeting rules
+type TargetingRuleCompiles struct {
+	Name string
+	Rule string
+}
+
+// IsValid validates that the targeting rule compiles
+func (v *TargetingRuleCompiles) IsValid(errors *validate.Errors) {
+	if _, err := targeting.CompileCached(v.Rule); err != nil {
+		errors.Add(validators.GenerateKey(v.Name), err.Error())
+	}
 }
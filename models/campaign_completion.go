@@ -0,0 +1,224 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// CampaignCompletion records when a user answered the last required question in a campaign, or in a run of
+// a recurring campaign
+type CampaignCompletion struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CampaignID  uuid.UUID  `json:"campaign_id" db:"campaign_id"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	RunID       nulls.UUID `json:"run_id" db:"run_id"`
+	CompletedAt time.Time  `json:"completed_at" db:"completed_at"`
+}
+
+// String is not required by pop and may be deleted
+func (c CampaignCompletion) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// CampaignCompletions is not required by pop and may be deleted
+type CampaignCompletions []CampaignCompletion
+
+// String is not required by pop and may be deleted
+func (c CampaignCompletions) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (c *CampaignCompletion) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: c.CampaignID, Name: "CampaignID"},
+		&validators.StringIsPresent{Field: c.UserID, Name: "UserID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (c *CampaignCompletion) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (c *CampaignCompletion) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// Progress is how far a user has got through a campaign.  Answered counts every enabled question the user
+// has responded to and Required the questions they have to answer to complete it.  Remaining lists the
+// required questions they haven't answered yet.  A campaign with no questions marked required needs all
+// of its enabled questions answered.
+type Progress struct {
+	CampaignID  uuid.UUID   `json:"campaign_id"`
+	UserID      string      `json:"user_id"`
+	RunID       nulls.UUID  `json:"run_id"`
+	Answered    int         `json:"answered"`
+	Required    int         `json:"required"`
+	Remaining   []uuid.UUID `json:"remaining"`
+	Complete    bool        `json:"complete"`
+	CompletedAt nulls.Time  `json:"completed_at"`
+}
+
+// RequiredQuestions returns the IDs of the questions to answer to complete the campaign, in order
+func RequiredQuestions(questions Questions) []uuid.UUID {
+	required, all := []uuid.UUID{}, []uuid.UUID{}
+	for _, q := range questions {
+		if !q.Enabled {
+			continue
+		}
+
+		all = append(all, q.ID)
+		if q.Required {
+			required = append(required, q.ID)
+		}
+	}
+
+	if len(required) == 0 {
+		return all
+	}
+	return required
+}
+
+// CampaignProgress works out how far the user has got through the campaign, counting only responses in the
+// given run for recurring campaigns
+func CampaignProgress(tx *pop.Connection, campaignID uuid.UUID, userID string, runID nulls.UUID) (*Progress, error) {
+	questions := Questions{}
+	if err := tx.Where("campaign_id = ?", campaignID).Order("position, created_at").All(&questions); err != nil {
+		return nil, err
+	}
+
+	answered := []uuid.UUID{}
+	query := "SELECT DISTINCT question_id FROM responses WHERE user_id = ? AND run_id <=> ? AND question_id IN (SELECT id FROM questions WHERE campaign_id = ? AND enabled = true)"
+	if err := tx.Store.Select(&answered, query, userID, runID, campaignID); err != nil {
+		return nil, err
+	}
+
+	done := map[uuid.UUID]bool{}
+	for _, id := range answered {
+		done[id] = true
+	}
+
+	required := RequiredQuestions(questions)
+	progress := &Progress{
+		CampaignID: campaignID,
+		UserID:     userID,
+		RunID:      runID,
+		Answered:   len(answered),
+		Required:   len(required),
+		Remaining:  []uuid.UUID{},
+	}
+
+	for _, id := range required {
+		if !done[id] {
+			progress.Remaining = append(progress.Remaining, id)
+		}
+	}
+	progress.Complete = len(required) > 0 && len(progress.Remaining) == 0
+
+	completion := CampaignCompletion{}
+	err := tx.Where("campaign_id = ?", campaignID).Where("user_id = ?", userID).Where("run_id <=> ?", runID).First(&completion)
+	if err == nil {
+		progress.CompletedAt = nulls.NewTime(completion.CompletedAt)
+	}
+
+	return progress, nil
+}
+
+// RecordCompletion records the time the user completed the campaign, the first time their progress shows
+// it complete.  Responses hold the campaign's row lock when this runs, so a completion is only recorded once.
+func RecordCompletion(tx *pop.Connection, campaignID uuid.UUID, userID string, runID nulls.UUID) error {
+	progress, err := CampaignProgress(tx, campaignID, userID, runID)
+	if err != nil {
+		return err
+	}
+
+	if !progress.Complete || progress.CompletedAt.Valid {
+		return nil
+	}
+
+	return tx.Create(&CampaignCompletion{CampaignID: campaignID, UserID: userID, RunID: runID, CompletedAt: time.Now()})
+}
+
+// RecheckCompletion removes the user's completion of the campaign when their progress no longer shows it
+// complete, such as after they retract a response to a required question
+func RecheckCompletion(tx *pop.Connection, campaignID uuid.UUID, userID string, runID nulls.UUID) error {
+	progress, err := CampaignProgress(tx, campaignID, userID, runID)
+	if err != nil {
+		return err
+	}
+
+	if progress.Complete {
+		return nil
+	}
+
+	return tx.RawQuery("DELETE FROM campaign_completions WHERE campaign_id = ? AND user_id = ? AND run_id <=> ?", campaignID, userID, runID).Exec()
+}
+
+// CompletionStats is how many users have got how far through a campaign, or one run of a recurring
+// campaign.  Respondents is the number of users who submitted a response, Started the number who
+// submitted a response or saved a draft, and Completed the number who answered every required question.
+type CompletionStats struct {
+	RunID          nulls.UUID `json:"run_id"`
+	Respondents    int        `json:"respondents"`
+	Started        int        `json:"started"`
+	Completed      int        `json:"completed"`
+	CompletionRate float64    `json:"completion_rate"`
+	Suppressed     []string   `json:"suppressed,omitempty"`
+}
+
+// Suppress hides the counts small enough to single out a respondent, for anonymous campaigns.  The
+// completion rate is hidden along with them, since it could be used to work them out.
+func (s *CompletionStats) Suppress(min int) {
+	s.Suppressed = SuppressSmallFields(map[string]*int{"respondents": &s.Respondents, "started": &s.Started, "completed": &s.Completed}, min)
+	if len(s.Suppressed) > 0 {
+		s.CompletionRate = 0
+	}
+}
+
+// CampaignCompletionStats counts the users who responded to, started and completed a campaign.  For recurring
+// campaigns only the given run is counted, and drafts count toward the run they were saved in.
+func CampaignCompletionStats(tx *pop.Connection, campaignID uuid.UUID, run *CampaignRun) (*CompletionStats, error) {
+	stats := &CompletionStats{}
+	drafts := "SELECT user_id FROM drafts WHERE question_id IN (SELECT id FROM questions WHERE campaign_id = ?)"
+	draftArgs := []interface{}{campaignID}
+	if run != nil {
+		stats.RunID = nulls.NewUUID(run.ID)
+		drafts += " AND updated_at >= ? AND updated_at < ?"
+		draftArgs = append(draftArgs, run.StartDate, run.EndDate)
+	}
+
+	responses := "SELECT user_id FROM responses WHERE run_id <=> ? AND question_id IN (SELECT id FROM questions WHERE campaign_id = ?)"
+	if err := tx.Store.Get(&stats.Respondents, "SELECT COUNT(DISTINCT user_id) FROM ("+responses+") respondents", stats.RunID, campaignID); err != nil {
+		return nil, err
+	}
+
+	args := append([]interface{}{stats.RunID, campaignID}, draftArgs...)
+	if err := tx.Store.Get(&stats.Started, "SELECT COUNT(*) FROM ("+responses+" UNION "+drafts+") started", args...); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT COUNT(DISTINCT user_id) FROM campaign_completions WHERE campaign_id = ? AND run_id <=> ?"
+	if err := tx.Store.Get(&stats.Completed, query, campaignID, stats.RunID); err != nil {
+		return nil, err
+	}
+
+	if stats.Started > 0 {
+		stats.CompletionRate = float64(stats.Completed) / float64(stats.Started)
+	}
+	return stats, nil
+}
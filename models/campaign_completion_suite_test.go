@@ -0,0 +1,80 @@
+package models_test
+
+import (
+	"time"
+
+	"github.com/YaleSpinup/tweaser/fixtures"
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/nulls"
+)
+
+func (ms *ModelSuite) Test_CampaignCompletion_Stats() {
+	campaign := fixtures.Campaign(ms.T(), "stats", 0)
+	first := fixtures.Question(ms.T(), campaign, "first", 0, 0)
+	second := fixtures.Question(ms.T(), campaign, "second", 0, 0)
+
+	for _, r := range []models.Response{
+		{UserID: "finished", QuestionID: first.ID, Text: "a"},
+		{UserID: "finished", QuestionID: second.ID, Text: "b"},
+		{UserID: "halfway", QuestionID: first.ID, Text: "c"},
+	} {
+		ms.NoError(models.DB.Create(&r))
+	}
+	ms.NoError(models.DB.Create(&models.Draft{UserID: "drafting", QuestionID: second.ID, Text: "d"}))
+
+	stats, err := models.CampaignCompletionStats(models.DB, campaign.ID, nil)
+	ms.NoError(err)
+	ms.Equal(2, stats.Respondents)
+	ms.Equal(3, stats.Started)
+	ms.Equal(1, stats.Completed)
+	ms.InDelta(1.0/3, stats.CompletionRate, 0.0001)
+}
+
+func (ms *ModelSuite) Test_CampaignCompletion_StatsByRun() {
+	campaign := fixtures.Campaign(ms.T(), "recurring", 0)
+	question := fixtures.Question(ms.T(), campaign, "only", 0, 0)
+
+	now := time.Now()
+	earlier := &models.CampaignRun{CampaignID: campaign.ID, Number: 1, StartDate: now.Add(-72 * time.Hour), EndDate: now.Add(-48 * time.Hour)}
+	current := &models.CampaignRun{CampaignID: campaign.ID, Number: 2, StartDate: now.Add(-time.Hour), EndDate: now.Add(time.Hour)}
+	ms.NoError(models.DB.Create(earlier))
+	ms.NoError(models.DB.Create(current))
+
+	ms.NoError(models.DB.Create(&models.Response{UserID: "someguy", QuestionID: question.ID, Text: "a", RunID: nulls.NewUUID(earlier.ID)}))
+	ms.NoError(models.DB.Create(&models.Response{UserID: "someotherguy", QuestionID: question.ID, Text: "b", RunID: nulls.NewUUID(earlier.ID)}))
+	ms.NoError(models.DB.Create(&models.Response{UserID: "someguy", QuestionID: question.ID, Text: "c", RunID: nulls.NewUUID(current.ID)}))
+
+	stats, err := models.CampaignCompletionStats(models.DB, campaign.ID, current)
+	ms.NoError(err)
+	ms.Equal(nulls.NewUUID(current.ID), stats.RunID)
+	ms.Equal(1, stats.Respondents)
+	ms.Equal(1, stats.Completed)
+
+	stats, err = models.CampaignCompletionStats(models.DB, campaign.ID, earlier)
+	ms.NoError(err)
+	ms.Equal(2, stats.Respondents)
+	ms.Equal(2, stats.Completed)
+}
+
+func (ms *ModelSuite) Test_CampaignCompletion_RecheckCompletion() {
+	campaign := fixtures.Campaign(ms.T(), "retract", 0)
+	first := fixtures.Question(ms.T(), campaign, "first", 0, 0)
+	second := fixtures.Question(ms.T(), campaign, "second", 0, 0)
+
+	retracted := &models.Response{UserID: "someguy", QuestionID: first.ID, Text: "a"}
+	ms.NoError(models.DB.Create(retracted))
+	ms.NoError(models.DB.Create(&models.Response{UserID: "someguy", QuestionID: second.ID, Text: "b"}))
+
+	// still complete, so nothing changes
+	ms.NoError(models.RecheckCompletion(models.DB, campaign.ID, "someguy", nulls.UUID{}))
+	count, err := models.DB.Where("campaign_id = ?", campaign.ID).Count(&models.CampaignCompletions{})
+	ms.NoError(err)
+	ms.Equal(1, count)
+
+	ms.NoError(models.DB.Destroy(retracted))
+	ms.NoError(models.RecheckCompletion(models.DB, campaign.ID, "someguy", nulls.UUID{}))
+
+	count, err = models.DB.Where("campaign_id = ?", campaign.ID).Count(&models.CampaignCompletions{})
+	ms.NoError(err)
+	ms.Equal(0, count)
+}
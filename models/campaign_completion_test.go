@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func Test_CampaignCompletion(t *testing.T) {
+	t.Log("This test needs to be implemented!")
+}
+
+func Test_RequiredQuestions(t *testing.T) {
+	q1, q2, q3 := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+
+	questions := Questions{
+		{ID: q1, Enabled: true},
+		{ID: q2, Enabled: true, Required: true},
+		{ID: q3, Enabled: false, Required: true},
+	}
+
+	if required := RequiredQuestions(questions); len(required) != 1 || required[0] != q2 {
+		t.Errorf("expected only the enabled required question, got %v", required)
+	}
+
+	// with nothing marked required, every enabled question is
+	questions[1].Required = false
+	if required := RequiredQuestions(questions); len(required) != 2 || required[0] != q1 || required[1] != q2 {
+		t.Errorf("expected every enabled question, got %v", required)
+	}
+
+	if required := RequiredQuestions(Questions{}); len(required) != 0 {
+		t.Errorf("expected no required questions, got %v", required)
+	}
+}
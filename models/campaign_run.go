@@ -0,0 +1,297 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrNoOpenRun is returned when a recurring campaign is between runs
+var ErrNoOpenRun = errors.New("campaign has no open run")
+
+// CampaignRun is one run of a recurring campaign, with its own window and set of responses
+type CampaignRun struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	CampaignID uuid.UUID `json:"campaign_id" db:"campaign_id"`
+	Number     int       `json:"number" db:"number"`
+	StartDate  time.Time `json:"start_date" db:"start_date"`
+	EndDate    time.Time `json:"end_date" db:"end_date"`
+}
+
+// String is not required by pop and may be deleted
+func (c CampaignRun) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// CampaignRuns is not required by pop and may be deleted
+type CampaignRuns []CampaignRun
+
+// String is not required by pop and may be deleted
+func (c CampaignRuns) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (c *CampaignRun) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: c.CampaignID, Name: "CampaignID"},
+		&validators.TimeIsBeforeTime{FirstName: "StartDate", FirstTime: c.StartDate, SecondName: "EndDate", SecondTime: c.EndDate},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (c *CampaignRun) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (c *CampaignRun) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// Recurring returns true if the campaign runs on a schedule
+func (c *Campaign) Recurring() bool {
+	return c.Recurrence.Valid && c.Recurrence.String != ""
+}
+
+// RunWindows returns the runs of a recurring campaign that have started by the given time.  The first run
+// starts with the campaign, each run lasts RunDurationSeconds, and no run starts or ends after the campaign.
+func (c *Campaign) RunWindows(until time.Time) (CampaignRuns, error) {
+	rec, err := ParseRecurrence(c.Recurrence.String)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := time.Duration(c.RunDurationSeconds.Int) * time.Second
+
+	// runs start at the same wall clock time in the campaign's time zone, whatever the offset is that day
+	first := c.StartDate.In(c.Location())
+
+	runs := CampaignRuns{}
+	for n := 0; rec.Count == 0 || n < rec.Count; n++ {
+		start := rec.Start(first, n).UTC()
+		if !start.Before(c.EndDate) || start.After(until) {
+			break
+		}
+
+		end := start.Add(duration)
+		if end.After(c.EndDate) {
+			end = c.EndDate
+		}
+
+		runs = append(runs, CampaignRun{CampaignID: c.ID, Number: n + 1, StartDate: start, EndDate: end})
+	}
+	return runs, nil
+}
+
+// RunLookahead is how far ahead runs are created, so each run exists before it opens
+var RunLookahead = time.Hour
+
+// AfterSave creates the runs of a recurring campaign that start soon, so a campaign that is saved with a
+// run already open can be served straight away
+func (c *Campaign) AfterSave(tx *pop.Connection) error {
+	return MaterializeRuns(tx, c, time.Now().Add(RunLookahead))
+}
+
+// MaterializeRuns creates the runs of a recurring campaign that start by the given time.  Runs that already
+// exist are left alone, so a run keeps its window even if the recurrence changes later.
+func MaterializeRuns(tx *pop.Connection, c *Campaign, until time.Time) error {
+	if !c.Recurring() {
+		return nil
+	}
+
+	windows, err := c.RunWindows(until)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, w := range windows {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+
+		query := "INSERT IGNORE INTO campaign_runs (id, campaign_id, number, start_date, end_date, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+		if err := tx.RawQuery(query, id, c.ID, w.Number, w.StartDate, w.EndDate, now, now).Exec(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MaterializeAllRuns creates the runs that start by the given time for every recurring campaign that hasn't ended
+func MaterializeAllRuns(tx *pop.Connection, until time.Time) error {
+	campaigns := Campaigns{}
+	if err := tx.Where("recurrence IS NOT NULL AND recurrence <> ''").Where("end_date > ?", time.Now()).All(&campaigns); err != nil {
+		return err
+	}
+
+	for i := range campaigns {
+		if err := MaterializeRuns(tx, &campaigns[i], until); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CurrentRun returns the run of a recurring campaign that is open at the given time, or nil between runs.
+// Runs are created ahead of time when the campaign is saved and by a background job.
+func CurrentRun(tx *pop.Connection, c *Campaign, now time.Time) (*CampaignRun, error) {
+	run := &CampaignRun{}
+	err := tx.Where("campaign_id = ?", c.ID).Where("start_date <= ?", now).Where("end_date > ?", now).First(run)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return run, nil
+}
+
+// RunFor returns the open run of the campaign a response is for.  It returns an invalid ID for campaigns
+// that don't recur and ErrNoOpenRun for recurring campaigns that are between runs.
+func RunFor(tx *pop.Connection, campaignID uuid.UUID, now time.Time) (nulls.UUID, error) {
+	campaign := &Campaign{}
+	if err := tx.Find(campaign, campaignID); err != nil {
+		return nulls.UUID{}, err
+	}
+
+	if !campaign.Recurring() {
+		return nulls.UUID{}, nil
+	}
+
+	run, err := CurrentRun(tx, campaign, now)
+	if err != nil {
+		return nulls.UUID{}, err
+	}
+
+	if run == nil {
+		return nulls.UUID{}, ErrNoOpenRun
+	}
+	return nulls.NewUUID(run.ID), nil
+}
+
+// RecurrenceIsValid is a custom validator for the recurrence of a campaign
+type RecurrenceIsValid struct {
+	Name     string
+	Rule     nulls.String
+	Duration nulls.Int
+}
+
+// IsValid validates that the recurrence parses and that each run has a duration that ends before the next run starts
+func (v *RecurrenceIsValid) IsValid(errors *validate.Errors) {
+	if !v.Rule.Valid || v.Rule.String == "" {
+		return
+	}
+
+	key := validators.GenerateKey(v.Name)
+	rec, err := ParseRecurrence(v.Rule.String)
+	if err != nil {
+		errors.Add(key, err.Error())
+		return
+	}
+
+	if !v.Duration.Valid || v.Duration.Int < 1 {
+		errors.Add(key, "RunDurationSeconds must be set for a recurring campaign.")
+		return
+	}
+
+	if time.Duration(v.Duration.Int)*time.Second > rec.Period() {
+		errors.Add(key, fmt.Sprintf("RunDurationSeconds must not be longer than the %s between runs.", rec.Period()))
+	}
+}
+
+// RunStats is how one run of a recurring campaign did.  Questions holds the number of responses to
+// each question and Count the number of times each answer was chosen during the run.
+type RunStats struct {
+	CampaignRun
+	Respondents int            `json:"respondents"`
+	Questions   map[string]int `json:"questions"`
+	Count       map[string]int `json:"count"`
+	Suppressed  []string       `json:"suppressed,omitempty"`
+}
+
+// Suppress hides the counts small enough to single out a respondent, for anonymous campaigns
+func (s *RunStats) Suppress(min int) {
+	var questions, answers []string
+	s.Suppressed = SuppressSmallFields(map[string]*int{"respondents": &s.Respondents}, min)
+	s.Questions, questions = SuppressSmallCounts(s.Questions, min)
+	s.Count, answers = SuppressSmallCounts(s.Count, min)
+	s.Suppressed = append(append(s.Suppressed, questions...), answers...)
+}
+
+// RunReport breaks down the responses to a recurring campaign by run so the runs can be compared.  Runs that
+// haven't started yet are left out.
+func RunReport(tx *pop.Connection, c *Campaign, now time.Time) ([]RunStats, error) {
+	runs := CampaignRuns{}
+	if err := tx.Where("campaign_id = ?", c.ID).Where("start_date <= ?", now).Order("number").All(&runs); err != nil {
+		return nil, err
+	}
+
+	report := []RunStats{}
+	for _, run := range runs {
+		stats := RunStats{CampaignRun: run, Questions: map[string]int{}, Count: map[string]int{}}
+		if err := tx.Store.Get(&stats.Respondents, "SELECT COUNT(DISTINCT user_id) FROM responses WHERE run_id = ?", run.ID); err != nil {
+			return nil, err
+		}
+
+		rows := []struct {
+			ID    uuid.UUID `db:"id"`
+			Count int       `db:"count"`
+		}{}
+
+		if err := tx.Store.Select(&rows, "SELECT question_id AS id, COUNT(*) AS count FROM responses WHERE run_id = ? GROUP BY question_id", run.ID); err != nil {
+			return nil, err
+		}
+
+		for _, r := range rows {
+			stats.Questions[r.ID.String()] = r.Count
+		}
+
+		rows = rows[:0]
+		query := `SELECT response_answers.answer_id AS id, COUNT(*) AS count FROM response_answers
+			JOIN responses ON responses.id = response_answers.response_id
+			WHERE responses.run_id = ? GROUP BY response_answers.answer_id`
+		if err := tx.Store.Select(&rows, query, run.ID); err != nil {
+			return nil, err
+		}
+
+		for _, r := range rows {
+			stats.Count[r.ID.String()] = r.Count
+		}
+
+		report = append(report, stats)
+	}
+	return report, nil
+}
+
+// LatestRun returns the most recent run of a recurring campaign to have started by the given time, or nil
+// if none has
+func LatestRun(tx *pop.Connection, c *Campaign, now time.Time) (*CampaignRun, error) {
+	run := &CampaignRun{}
+	err := tx.Where("campaign_id = ?", c.ID).Where("start_date <= ?", now).Order("number DESC").First(run)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return run, nil
+}
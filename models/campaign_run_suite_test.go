@@ -0,0 +1,57 @@
+package models_test
+
+import (
+	"time"
+
+	"github.com/YaleSpinup/tweaser/fixtures"
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/nulls"
+)
+
+func (ms *ModelSuite) Test_CampaignRun_AnsweredInRun() {
+	campaign := fixtures.Campaign(ms.T(), "recurring", 0)
+	question := fixtures.Question(ms.T(), campaign, "How are we doing?", 0, 0)
+
+	// answered before the campaign was made recurring, so the response isn't in any run
+	ms.NoError(models.DB.Create(&models.Response{UserID: "someguy", QuestionID: question.ID, Text: "fine"}))
+
+	campaign.Recurrence = nulls.NewString("FREQ=DAILY")
+	campaign.RunDurationSeconds = nulls.NewInt(2 * 60 * 60)
+	ms.NoError(models.DB.Update(campaign))
+
+	// saving the campaign creates its open run, so reading the feed doesn't have to
+	run, err := models.CurrentRun(models.DB, campaign, time.Now())
+	ms.NoError(err)
+	ms.NotNil(run)
+	ms.Equal(1, run.Number)
+
+	answered, err := models.AnsweredInCampaign(models.DB, campaign.ID, "someguy", nulls.NewUUID(run.ID))
+	ms.NoError(err)
+	ms.False(answered[question.ID], "a response outside of any run shouldn't count for the open run")
+
+	ms.NoError(models.DB.Create(&models.Response{UserID: "someguy", QuestionID: question.ID, Text: "better", RunID: nulls.NewUUID(run.ID)}))
+
+	answered, err = models.AnsweredInCampaign(models.DB, campaign.ID, "someguy", nulls.NewUUID(run.ID))
+	ms.NoError(err)
+	ms.True(answered[question.ID])
+}
+
+func (ms *ModelSuite) Test_CampaignRun_MaterializeAllRuns() {
+	campaign := fixtures.Campaign(ms.T(), "daily", 0)
+	campaign.Recurrence = nulls.NewString("FREQ=DAILY")
+	campaign.RunDurationSeconds = nulls.NewInt(60 * 60)
+	campaign.EndDate = time.Now().Add(72 * time.Hour)
+	ms.NoError(models.DB.Update(campaign))
+
+	// the second run starts in 23 hours, so it only exists once the job looks that far ahead
+	count, err := models.DB.Where("campaign_id = ?", campaign.ID).Count(&models.CampaignRuns{})
+	ms.NoError(err)
+	ms.Equal(1, count)
+
+	ms.NoError(models.MaterializeAllRuns(models.DB, time.Now().Add(24*time.Hour)))
+	ms.NoError(models.MaterializeAllRuns(models.DB, time.Now().Add(24*time.Hour)))
+
+	count, err = models.DB.Where("campaign_id = ?", campaign.ID).Count(&models.CampaignRuns{})
+	ms.NoError(err)
+	ms.Equal(2, count)
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/validate/v3"
+)
+
+func Test_CampaignRun(t *testing.T) {
+	t.Log("This test needs to be implemented!")
+}
+
+func Test_RunWindows(t *testing.T) {
+	start := time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+	campaign := Campaign{
+		StartDate:          start,
+		EndDate:            time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC),
+		Recurrence:         nulls.NewString("FREQ=MONTHLY;INTERVAL=3"),
+		RunDurationSeconds: nulls.NewInt(14 * 24 * 60 * 60),
+	}
+
+	runs, err := campaign.RunWindows(time.Date(2026, time.August, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []time.Time{start, start.AddDate(0, 3, 0), start.AddDate(0, 6, 0)}
+	if len(runs) != len(expected) {
+		t.Fatalf("expected %d runs, got %d: %v", len(expected), len(runs), runs)
+	}
+
+	for i, run := range runs {
+		if run.Number != i+1 || !run.StartDate.Equal(expected[i]) || !run.EndDate.Equal(expected[i].AddDate(0, 0, 14)) {
+			t.Errorf("unexpected run %d: %+v", i+1, run)
+		}
+	}
+
+	// the last run is cut off at the end of the campaign
+	campaign.EndDate = time.Date(2026, time.October, 10, 0, 0, 0, 0, time.UTC)
+	runs, err = campaign.RunWindows(campaign.EndDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runs) != 4 || !runs[3].EndDate.Equal(campaign.EndDate) {
+		t.Errorf("expected 4 runs with the last ending with the campaign, got %v", runs)
+	}
+
+	campaign.Recurrence = nulls.NewString("FREQ=MONTHLY;INTERVAL=3;COUNT=2")
+	if runs, _ = campaign.RunWindows(campaign.EndDate); len(runs) != 2 {
+		t.Errorf("expected COUNT to limit the runs to 2, got %d", len(runs))
+	}
+}
+
+func Test_RecurrenceIsValid(t *testing.T) {
+	tests := []struct {
+		rule     nulls.String
+		duration nulls.Int
+		valid    bool
+	}{
+		{nulls.String{}, nulls.Int{}, true},
+		{nulls.NewString("FREQ=WEEKLY"), nulls.NewInt(3 * 24 * 60 * 60), true},
+		{nulls.NewString("FREQ=WEEKLY"), nulls.Int{}, false},
+		{nulls.NewString("FREQ=WEEKLY"), nulls.NewInt(8 * 24 * 60 * 60), false},
+		{nulls.NewString("FREQ=HOURLY"), nulls.NewInt(60), false},
+	}
+
+	for _, tt := range tests {
+		errs := validate.Validate(&RecurrenceIsValid{Name: "Recurrence", Rule: tt.rule, Duration: tt.duration})
+		if errs.HasAny() == tt.valid {
+			t.Errorf("expected %v with duration %v valid=%t, got %v", tt.rule, tt.duration, tt.valid, errs)
+		}
+	}
+}
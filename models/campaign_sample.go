@@ -0,0 +1,101 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// CampaignSample records whether a user who was eligible for a sampled campaign fell inside the sample
+type CampaignSample struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	CampaignID uuid.UUID `json:"campaign_id" db:"campaign_id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	Sampled    bool      `json:"sampled" db:"sampled"`
+}
+
+// String is not required by pop and may be deleted
+func (c CampaignSample) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// CampaignSamples is not required by pop and may be deleted
+type CampaignSamples []CampaignSample
+
+// String is not required by pop and may be deleted
+func (c CampaignSamples) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (c *CampaignSample) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: c.CampaignID, Name: "CampaignID"},
+		&validators.StringIsPresent{Field: c.UserID, Name: "UserID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (c *CampaignSample) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (c *CampaignSample) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// SampleBucket deterministically places a user in one of 100 buckets for a campaign.  Hashing with
+// the campaign ID keeps the same users from landing in the first few percent of every campaign.
+func SampleBucket(campaignID uuid.UUID, userID string) int {
+	sum := sha256.Sum256([]byte(campaignID.String() + ":" + userID))
+	return int(binary.BigEndian.Uint64(sum[:8]) % 100)
+}
+
+// InSample returns true if the user falls inside the campaign's sample.  Users are in the sample when
+// their bucket is below the sample percent, so raising the percent only ever adds users.
+func (c *Campaign) InSample(userID string) bool {
+	if !c.SamplePercent.Valid {
+		return true
+	}
+	return SampleBucket(c.ID, userID) < c.SamplePercent.Int
+}
+
+// RecordSample records that the user was eligible for the campaign and whether they were sampled
+func RecordSample(tx *pop.Connection, campaignID uuid.UUID, userID string, sampled bool) error {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return tx.RawQuery(`INSERT INTO campaign_samples (id, campaign_id, user_id, sampled, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE sampled = VALUES(sampled), updated_at = VALUES(updated_at)`, id, campaignID, userID, sampled, now, now).Exec()
+}
+
+// SamplePercentInRange is a custom validator for the optional campaign sample percent
+type SamplePercentInRange struct {
+	Name  string
+	Field nulls.Int
+}
+
+// IsValid validates that the sample percent, when set, is between 0 and 100
+func (v *SamplePercentInRange) IsValid(errors *validate.Errors) {
+	if v.Field.Valid && (v.Field.Int < 0 || v.Field.Int > 100) {
+		errors.Add(validators.GenerateKey(v.Name), "SamplePercent must be between 0 and 100.")
+	}
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
+)
+
+func Test_CampaignSample(t *testing.T) {
+	t.Log("This test needs to be implemented!")
+}
+
+func Test_InSample(t *testing.T) {
+	// a fixed campaign keeps the sample sizes the same from run to run
+	campaign := Campaign{ID: uuid.Must(uuid.FromString("6f1c2a9e-3b4d-4c5e-8f70-9a1b2c3d4e5f"))}
+
+	users := []string{}
+	for i := 0; i < 1000; i++ {
+		users = append(users, fmt.Sprintf("someuser%d", i))
+	}
+
+	for _, u := range users {
+		if !campaign.InSample(u) {
+			t.Fatalf("expected %s to be in an unsampled campaign", u)
+		}
+	}
+
+	// ramping up should only ever add users
+	previous := map[string]bool{}
+	for _, percent := range []int{0, 10, 25, 50, 100} {
+		campaign.SamplePercent = nulls.NewInt(percent)
+
+		count := 0
+		for _, u := range users {
+			in := campaign.InSample(u)
+			if previous[u] && !in {
+				t.Errorf("%s dropped out of the sample going to %d%%", u, percent)
+			}
+			previous[u] = in
+
+			if in {
+				count++
+			}
+		}
+
+		if percent == 0 && count != 0 {
+			t.Errorf("expected nobody in a 0%% sample, got %d", count)
+		}
+
+		if percent == 100 && count != len(users) {
+			t.Errorf("expected everyone in a 100%% sample, got %d", count)
+		}
+
+		// allow some slack for the hash distribution
+		if expected := percent * len(users) / 100; count < expected-50 || count > expected+50 {
+			t.Errorf("expected about %d users in a %d%% sample, got %d", expected, percent, count)
+		}
+	}
+}
+
+func Test_SampleBucket(t *testing.T) {
+	a, b := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+
+	if SampleBucket(a, "someguy") != SampleBucket(a, "someguy") {
+		t.Error("expected the same bucket for the same campaign and user")
+	}
+
+	// the same user should not land in the same bucket for every campaign
+	same := 0
+	for i := 0; i < 100; i++ {
+		user := fmt.Sprintf("someuser%d", i)
+		if SampleBucket(a, user) == SampleBucket(b, user) {
+			same++
+		}
+	}
+
+	if same > 10 {
+		t.Errorf("expected buckets to differ between campaigns, %d of 100 users matched", same)
+	}
+}
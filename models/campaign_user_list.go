@@ -0,0 +1,116 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// AllowList limits a campaign to the users on it
+	AllowList = "allow"
+
+	// DenyList keeps a campaign from the users on it
+	DenyList = "deny"
+
+	// listInsertBatch is the number of user IDs inserted per statement when uploading a list
+	listInsertBatch = 1000
+
+	// NotDeniedSQL is a where clause fragment on campaigns that leaves out campaigns denying the user.  It takes
+	// the user ID and their SuppressionID, which keeps the deny list entries of users who were erased.
+	NotDeniedSQL = "NOT EXISTS (SELECT 1 FROM campaign_user_lists WHERE campaign_user_lists.campaign_id = campaigns.id AND campaign_user_lists.list = 'deny' AND campaign_user_lists.user_id IN (?, ?))"
+
+	// AllowedSQL is a where clause fragment on campaigns that leaves out campaigns with an allow list the user isn't on
+	AllowedSQL = "(NOT EXISTS (SELECT 1 FROM campaign_user_lists WHERE campaign_user_lists.campaign_id = campaigns.id AND campaign_user_lists.list = 'allow') OR EXISTS (SELECT 1 FROM campaign_user_lists WHERE campaign_user_lists.campaign_id = campaigns.id AND campaign_user_lists.list = 'allow' AND campaign_user_lists.user_id = ?))"
+)
+
+// CampaignUserList is a user on a campaign's allow or deny list
+type CampaignUserList struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	CampaignID uuid.UUID `json:"campaign_id" db:"campaign_id"`
+	List       string    `json:"list" db:"list"`
+	UserID     string    `json:"user_id" db:"user_id"`
+}
+
+// String is not required by pop and may be deleted
+func (c CampaignUserList) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// CampaignUserLists is not required by pop and may be deleted
+type CampaignUserLists []CampaignUserList
+
+// String is not required by pop and may be deleted
+func (c CampaignUserLists) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (c *CampaignUserList) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: c.CampaignID, Name: "CampaignID"},
+		&validators.StringInclusion{Field: c.List, Name: "List", List: []string{AllowList, DenyList}},
+		&validators.StringIsPresent{Field: c.UserID, Name: "UserID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (c *CampaignUserList) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (c *CampaignUserList) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// AddToUserList adds the user IDs to the campaign's list in batches, skipping any that are already on it.
+// It returns the number of user IDs added.
+func AddToUserList(tx *pop.Connection, campaignID uuid.UUID, list string, userIDs []string) (int, error) {
+	added := 0
+	now := time.Now()
+	for start := 0; start < len(userIDs); start += listInsertBatch {
+		end := start + listInsertBatch
+		if end > len(userIDs) {
+			end = len(userIDs)
+		}
+
+		values := []string{}
+		args := []interface{}{}
+		for _, userID := range userIDs[start:end] {
+			id, err := uuid.NewV4()
+			if err != nil {
+				return added, err
+			}
+
+			values = append(values, "(?, ?, ?, ?, ?, ?)")
+			args = append(args, id, campaignID, list, userID, now, now)
+		}
+
+		query := "INSERT IGNORE INTO campaign_user_lists (id, campaign_id, list, user_id, created_at, updated_at) VALUES " + strings.Join(values, ", ")
+		result, err := tx.Store.Exec(query, args...)
+		if err != nil {
+			return added, err
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return added, err
+		}
+		added += int(rows)
+	}
+
+	return added, nil
+}
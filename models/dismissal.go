@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Dismissal is a user hiding a question from their question list, either permanently or until SnoozedUntil
+type Dismissal struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	UserID       string     `json:"user_id" db:"user_id"`
+	Question     Question   `belongs_to:"question" json:"-"`
+	QuestionID   uuid.UUID  `json:"question_id" db:"question_id"`
+	SnoozedUntil nulls.Time `json:"snoozed_until" db:"snoozed_until"`
+	Snooze       string     `json:"snooze,omitempty" db:"-"`
+}
+
+// String is not required by pop and may be deleted
+func (d Dismissal) String() string {
+	jd, _ := json.Marshal(d)
+	return string(jd)
+}
+
+// Dismissals is not required by pop and may be deleted
+type Dismissals []Dismissal
+
+// String is not required by pop and may be deleted
+func (d Dismissals) String() string {
+	jd, _ := json.Marshal(d)
+	return string(jd)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (d *Dismissal) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: d.QuestionID, Name: "QuestionID"},
+		&validators.StringIsPresent{Field: d.UserID, Name: "UserID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (d *Dismissal) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (d *Dismissal) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// Snoozed returns true if the dismissal only hides the question for a while
+func (d *Dismissal) Snoozed() bool {
+	return d.SnoozedUntil.Valid
+}
+
+// DismissedQuestions returns the questions dismissed, or still snoozed, under any of the respondent IDs a
+// user goes by
+func DismissedQuestions(tx *pop.Connection, respondentIDs []string, now time.Time) (map[uuid.UUID]bool, error) {
+	dismissals := Dismissals{}
+	q := tx.Select("question_id").Where("user_id IN (?)", stringArgs(respondentIDs)...)
+	if err := q.Where("snoozed_until IS NULL OR snoozed_until > ?", now).All(&dismissals); err != nil {
+		return nil, err
+	}
+
+	dismissed := map[uuid.UUID]bool{}
+	for _, d := range dismissals {
+		dismissed[d.QuestionID] = true
+	}
+	return dismissed, nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Draft is a user's unsubmitted text for an input question.  Drafts are kept apart from responses so they
+// never show up in reports, and are deleted when the response is submitted.
+type Draft struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	Question   Question  `belongs_to:"question" json:"-"`
+	QuestionID uuid.UUID `json:"question_id" db:"question_id"`
+	Text       string    `json:"text" db:"text"`
+	Encrypted  bool      `json:"-" db:"encrypted"`
+}
+
+// String is not required by pop and may be deleted
+func (d Draft) String() string {
+	jd, _ := json.Marshal(d)
+	return string(jd)
+}
+
+// Drafts is not required by pop and may be deleted
+type Drafts []Draft
+
+// String is not required by pop and may be deleted
+func (d Drafts) String() string {
+	jd, _ := json.Marshal(d)
+	return string(jd)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (d *Draft) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: d.UserID, Name: "UserID"},
+		&validators.UUIDIsPresent{Field: d.QuestionID, Name: "QuestionID"},
+		&validators.StringInclusion{Field: d.Question.Type, List: []string{"input"}, Name: "QuestionType", Message: "Drafts can only be saved for input questions."},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (d *Draft) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (d *Draft) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// FindDraft returns the user's draft for a question, or nil if they don't have one
+func FindDraft(tx *pop.Connection, questionID uuid.UUID, userID string) (*Draft, error) {
+	draft := &Draft{}
+	if err := tx.Where("question_id = ?", questionID).Where("user_id = ?", userID).First(draft); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return draft, nil
+}
+
+// DeleteDraft deletes the user's draft for a question, if they have one
+func DeleteDraft(tx *pop.Connection, questionID uuid.UUID, userID string) error {
+	return tx.RawQuery("DELETE FROM drafts WHERE question_id = ? AND user_id = ?", questionID, userID).Exec()
+}
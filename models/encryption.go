@@ -0,0 +1,212 @@
+package models
+
+import (
+	"log"
+
+	"github.com/YaleSpinup/tweaser/helpers"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+)
+
+// TextKeys encrypts the free text users enter in responses, earlier response versions and drafts.  It is
+// configured with ENCRYPTION_KEYS, a comma separated list of id:key pairs, and ENCRYPTION_KEY_ID, the key
+// new text is encrypted with.  Text is stored as entered when no keys are set.
+var TextKeys = loadTextKeys()
+
+func loadTextKeys() *helpers.Keyring {
+	k, err := helpers.NewKeyring(envy.Get("ENCRYPTION_KEYS", ""), envy.Get("ENCRYPTION_KEY_ID", ""))
+	if err != nil {
+		log.Fatalf("ENCRYPTION_KEYS is invalid: %s", err)
+	}
+	return k
+}
+
+// encryptText replaces the text with its encrypted form before it is written to the database, and returns
+// whether it was encrypted
+func encryptText(text *string) (bool, error) {
+	enc, err := TextKeys.Encrypt(*text)
+	if err != nil {
+		return false, err
+	}
+
+	*text = enc
+	return TextKeys.Enabled(), nil
+}
+
+// decryptText replaces text read from the database with the text the user entered.  Only text stored with
+// its row's encrypted flag set is decrypted, so text a user entered that looks like an encrypted value is
+// never taken for one.
+func decryptText(text *string, encrypted bool) error {
+	if !encrypted {
+		return nil
+	}
+
+	dec, err := TextKeys.Decrypt(*text)
+	if err != nil {
+		return err
+	}
+
+	*text = dec
+	return nil
+}
+
+// BeforeSave redacts the response text, then encrypts it along with the redacted text
+func (r *Response) BeforeSave(tx *pop.Connection) error {
+	if err := r.redact(tx); err != nil {
+		return err
+	}
+
+	if _, err := encryptText(&r.RedactedText.String); err != nil {
+		return err
+	}
+
+	encrypted, err := encryptText(&r.Text)
+	r.Encrypted = encrypted
+	return err
+}
+
+// AfterSave puts back the response text that was encrypted for saving
+func (r *Response) AfterSave(tx *pop.Connection) error {
+	return r.AfterFind(tx)
+}
+
+// AfterFind decrypts the response text
+func (r *Response) AfterFind(tx *pop.Connection) error {
+	if err := decryptText(&r.Text, r.Encrypted); err != nil {
+		return err
+	}
+	return decryptText(&r.RedactedText.String, r.Encrypted)
+}
+
+// BeforeSave encrypts the text of the earlier version
+func (r *ResponseVersion) BeforeSave(tx *pop.Connection) error {
+	encrypted, err := encryptText(&r.Text)
+	r.Encrypted = encrypted
+	return err
+}
+
+// AfterSave puts back the version text that was encrypted for saving
+func (r *ResponseVersion) AfterSave(tx *pop.Connection) error {
+	return decryptText(&r.Text, r.Encrypted)
+}
+
+// AfterFind decrypts the text of the earlier version
+func (r *ResponseVersion) AfterFind(tx *pop.Connection) error {
+	return decryptText(&r.Text, r.Encrypted)
+}
+
+// BeforeSave encrypts the draft text
+func (d *Draft) BeforeSave(tx *pop.Connection) error {
+	encrypted, err := encryptText(&d.Text)
+	d.Encrypted = encrypted
+	return err
+}
+
+// AfterSave puts back the draft text that was encrypted for saving
+func (d *Draft) AfterSave(tx *pop.Connection) error {
+	return decryptText(&d.Text, d.Encrypted)
+}
+
+// AfterFind decrypts the draft text
+func (d *Draft) AfterFind(tx *pop.Connection) error {
+	return decryptText(&d.Text, d.Encrypted)
+}
+
+// encryptedText are the tables holding free text encrypted with TextKeys, and whether they also have a
+// redacted_text column.  A row's text and redacted text are encrypted together, as its encrypted flag
+// covers both.
+var encryptedText = []struct {
+	table    string
+	redacted bool
+}{
+	{"responses", true},
+	{"response_versions", false},
+	{"drafts", false},
+}
+
+// RekeyResult is the number of rows re-encrypted with the current key in each table, and the IDs of the
+// rows that were left alone because their text couldn't be decrypted
+type RekeyResult struct {
+	KeyID       string              `json:"key_id"`
+	Reencrypted map[string]int      `json:"reencrypted"`
+	Skipped     map[string][]string `json:"skipped,omitempty"`
+}
+
+// RekeyText re-encrypts text that is stored in plain text or with a key other than the current one,
+// so that older keys can be retired after a rotation.  Rows are read and written in batches, in ID
+// order.  Encrypted text that can't be decrypted, like text encrypted with a key that was already
+// removed, is skipped and reported instead of failing the whole run.
+func RekeyText(tx *pop.Connection, batchSize int) (*RekeyResult, error) {
+	result := &RekeyResult{KeyID: TextKeys.Current(), Reencrypted: map[string]int{}, Skipped: map[string][]string{}}
+	if !TextKeys.Enabled() {
+		return result, nil
+	}
+
+	current := "enc:" + TextKeys.Current() + ":"
+	for _, t := range encryptedText {
+		// a row needs rekeying when its text is in plain text or encrypted with another key
+		redacted, stale := "NULL", "(text <> '' AND (encrypted = false OR LEFT(text, ?) <> ?))"
+		if t.redacted {
+			redacted = "redacted_text"
+			stale = "(" + stale + " OR (redacted_text <> '' AND (encrypted = false OR LEFT(redacted_text, ?) <> ?)))"
+		}
+		query := "SELECT id, encrypted, text, " + redacted + " AS redacted_text FROM " + t.table + " WHERE " + stale + " AND id > ? ORDER BY id LIMIT ?"
+
+		after := ""
+		for {
+			args := []interface{}{len(current), current}
+			if t.redacted {
+				args = append(args, len(current), current)
+			}
+
+			rows := []struct {
+				ID           string       `db:"id"`
+				Encrypted    bool         `db:"encrypted"`
+				Text         string       `db:"text"`
+				RedactedText nulls.String `db:"redacted_text"`
+			}{}
+			if err := tx.Store.Select(&rows, query, append(args, after, batchSize)...); err != nil {
+				return nil, err
+			}
+
+			if len(rows) == 0 {
+				break
+			}
+
+			for _, row := range rows {
+				after = row.ID
+
+				if err := decryptText(&row.Text, row.Encrypted); err != nil {
+					result.Skipped[t.table] = append(result.Skipped[t.table], row.ID)
+					continue
+				}
+
+				if err := decryptText(&row.RedactedText.String, row.Encrypted); err != nil {
+					result.Skipped[t.table] = append(result.Skipped[t.table], row.ID)
+					continue
+				}
+
+				if _, err := encryptText(&row.Text); err != nil {
+					return nil, err
+				}
+
+				if _, err := encryptText(&row.RedactedText.String); err != nil {
+					return nil, err
+				}
+
+				update := tx.RawQuery("UPDATE "+t.table+" SET encrypted = true, text = ? WHERE id = ?", row.Text, row.ID)
+				if t.redacted {
+					update = tx.RawQuery("UPDATE "+t.table+" SET encrypted = true, text = ?, redacted_text = ? WHERE id = ?", row.Text, row.RedactedText, row.ID)
+				}
+
+				if err := update.Exec(); err != nil {
+					return nil, err
+				}
+				result.Reencrypted[t.table]++
+			}
+		}
+	}
+
+	return result, nil
+}
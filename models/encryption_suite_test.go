@@ -0,0 +1,113 @@
+package models_test
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/YaleSpinup/tweaser/fixtures"
+	"github.com/YaleSpinup/tweaser/helpers"
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gofrs/uuid"
+)
+
+// textKey returns a new random key as an id:key pair for ENCRYPTION_KEYS
+func (ms *ModelSuite) textKey(id string) string {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	ms.NoError(err)
+	return id + ":" + base64.StdEncoding.EncodeToString(key)
+}
+
+// insertDraft stores draft text as is, without encrypting it or flagging it encrypted
+func (ms *ModelSuite) insertDraft(question *models.Question, userID, text string) uuid.UUID {
+	id := uuid.Must(uuid.NewV4())
+	err := models.DB.RawQuery("INSERT INTO drafts (id, question_id, user_id, text, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		id, question.ID, userID, text, time.Now(), time.Now()).Exec()
+	ms.NoError(err)
+	return id
+}
+
+func (ms *ModelSuite) Test_RekeyText_MixedFormats() {
+	old, current := ms.textKey("old"), ms.textKey("new")
+
+	// text encrypted with the old key, before the rotation
+	keys, err := helpers.NewKeyring(old, "old")
+	ms.NoError(err)
+	defer func(k *helpers.Keyring) { models.TextKeys = k }(models.TextKeys)
+	models.TextKeys = keys
+
+	campaign := fixtures.Campaign(ms.T(), "rekey", 0)
+	question := fixtures.Question(ms.T(), campaign, "Anything else?", 0, 0)
+	response := &models.Response{UserID: "someguy", QuestionID: question.ID, Text: "encrypted"}
+	ms.NoError(models.DB.Create(response))
+
+	// text stored before encryption was turned on, some of it looking like an encrypted value
+	plain := ms.insertDraft(question, "someguy", "just text")
+	lookalike := ms.insertDraft(question, "someotherguy", "enc:a:b:c")
+
+	// text encrypted with a key that was removed since
+	removed, err := helpers.NewKeyring(ms.textKey("removed"), "")
+	ms.NoError(err)
+	models.TextKeys = removed
+	lost := &models.Draft{UserID: "thirdguy", QuestionID: question.ID, Text: "lost"}
+	ms.NoError(models.DB.Create(lost))
+
+	keys, err = helpers.NewKeyring(old+","+current, "new")
+	ms.NoError(err)
+	models.TextKeys = keys
+
+	// batches of one make sure a skipped row doesn't stop the rows after it
+	result, err := models.RekeyText(models.DB, 1)
+	ms.NoError(err)
+	ms.Equal(1, result.Reencrypted["responses"])
+	ms.Equal(2, result.Reencrypted["drafts"])
+	ms.Equal([]string{lost.ID.String()}, result.Skipped["drafts"])
+
+	rows := []struct {
+		ID   string `db:"id"`
+		Text string `db:"text"`
+	}{}
+	ms.NoError(models.DB.Store.Select(&rows, "SELECT id, text FROM drafts UNION ALL SELECT id, text FROM responses"))
+	ms.Len(rows, 4)
+	for _, row := range rows {
+		if row.ID == lost.ID.String() {
+			ms.True(strings.HasPrefix(row.Text, "enc:removed:"))
+			continue
+		}
+		ms.True(strings.HasPrefix(row.Text, "enc:new:"), "expected %s to be encrypted with the new key", row.ID)
+	}
+
+	reread := &models.Response{}
+	ms.NoError(models.DB.Find(reread, response.ID))
+	ms.Equal("encrypted", reread.Text)
+
+	draft := &models.Draft{}
+	ms.NoError(models.DB.Find(draft, plain))
+	ms.Equal("just text", draft.Text)
+
+	ms.NoError(models.DB.Find(draft, lookalike))
+	ms.Equal("enc:a:b:c", draft.Text)
+}
+
+func (ms *ModelSuite) Test_Encryption_LookalikeText() {
+	campaign := fixtures.Campaign(ms.T(), "lookalike", 0)
+	question := fixtures.Question(ms.T(), campaign, "Anything else?", 0, 0)
+
+	// text a user enters that looks encrypted is stored and read back as entered, with or without keys
+	defer func(k *helpers.Keyring) { models.TextKeys = k }(models.TextKeys)
+	for _, spec := range []string{"", ms.textKey("k1")} {
+		keys, err := helpers.NewKeyring(spec, "")
+		ms.NoError(err)
+		models.TextKeys = keys
+
+		response := &models.Response{UserID: "someguy-" + spec, QuestionID: question.ID, Text: "enc:a:b:c"}
+		ms.NoError(models.DB.Create(response))
+		ms.Equal("enc:a:b:c", response.Text)
+
+		reread := &models.Response{}
+		ms.NoError(models.DB.Find(reread, response.ID))
+		ms.Equal("enc:a:b:c", reread.Text)
+	}
+}
@@ -0,0 +1,10 @@
+package models
+
+// UserTableWheres maps each table erased with a user to the where clause that finds the user's rows, for tests
+func UserTableWheres() map[string]string {
+	wheres := map[string]string{}
+	for _, t := range userTables {
+		wheres[t.table] = t.where
+	}
+	return wheres
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+)
+
+// HistoryEntry is one of a user's responses with the question, campaign and answers it refers to spelled out
+type HistoryEntry struct {
+	ID           uuid.UUID       `json:"id" db:"id"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at" db:"updated_at"`
+	Version      int             `json:"version" db:"version"`
+	RunID        nulls.UUID      `json:"run_id" db:"run_id"`
+	CampaignID   uuid.UUID       `json:"campaign_id" db:"campaign_id"`
+	CampaignName string          `json:"campaign_name" db:"campaign_name"`
+	QuestionID   uuid.UUID       `json:"question_id" db:"question_id"`
+	QuestionText string          `json:"question_text" db:"question_text"`
+	Text         string          `json:"text" db:"text"`
+	Answers      []HistoryAnswer `json:"answers" db:"-"`
+	Editable     bool            `json:"editable" db:"-"`
+	Token        string          `json:"token,omitempty" db:"-"`
+	Encrypted    bool            `json:"-" db:"encrypted"`
+}
+
+// HistoryAnswer is an answer selected in a response
+type HistoryAnswer struct {
+	ResponseID uuid.UUID `json:"-" db:"response_id"`
+	ID         uuid.UUID `json:"id" db:"id"`
+	Text       string    `json:"text" db:"text"`
+}
+
+// ResponseHistory returns all of a user's responses, newest first
+func ResponseHistory(tx *pop.Connection, userID string) ([]HistoryEntry, error) {
+	entries := []HistoryEntry{}
+	query := `SELECT responses.id, responses.created_at, responses.updated_at, responses.version, responses.run_id,
+			campaigns.id AS campaign_id, campaigns.name AS campaign_name, questions.id AS question_id,
+			questions.text AS question_text, responses.text, responses.encrypted
+		FROM responses
+		JOIN questions ON questions.id = responses.question_id
+		JOIN campaigns ON campaigns.id = questions.campaign_id
+		WHERE responses.user_id = ?
+		ORDER BY responses.created_at DESC, responses.id`
+	if err := tx.Store.Select(&entries, query, userID); err != nil {
+		return nil, err
+	}
+
+	answers := []HistoryAnswer{}
+	query = `SELECT response_answers.response_id, answers.id, answers.text
+		FROM response_answers
+		JOIN answers ON answers.id = response_answers.answer_id
+		JOIN responses ON responses.id = response_answers.response_id
+		WHERE responses.user_id = ?
+		ORDER BY response_answers.created_at`
+	if err := tx.Store.Select(&answers, query, userID); err != nil {
+		return nil, err
+	}
+
+	byResponse := map[uuid.UUID][]HistoryAnswer{}
+	for _, a := range answers {
+		byResponse[a.ResponseID] = append(byResponse[a.ResponseID], a)
+	}
+
+	for i, e := range entries {
+		if err := decryptText(&entries[i].Text, e.Encrypted); err != nil {
+			return nil, err
+		}
+
+		entries[i].Answers = byResponse[e.ID]
+		if entries[i].Answers == nil {
+			entries[i].Answers = []HistoryAnswer{}
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,85 @@
+package models_test
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/YaleSpinup/tweaser/fixtures"
+	"github.com/YaleSpinup/tweaser/helpers"
+	"github.com/YaleSpinup/tweaser/models"
+)
+
+// useTextKeys encrypts text with a new random key until the returned function puts back the old keys
+func (ms *ModelSuite) useTextKeys() func() {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	ms.NoError(err)
+
+	keys, err := helpers.NewKeyring("test:"+base64.StdEncoding.EncodeToString(key), "")
+	ms.NoError(err)
+
+	old := models.TextKeys
+	models.TextKeys = keys
+	return func() { models.TextKeys = old }
+}
+
+func (ms *ModelSuite) Test_History_ResolvesEdits() {
+	campaign := fixtures.Campaign(ms.T(), "history", 0)
+	question := &models.Question{Text: "Pick one", CampaignID: campaign.ID, Enabled: true, Type: "single"}
+	ms.NoError(models.DB.Create(question))
+
+	yes := &models.Answer{Text: "yes", Enabled: true, QuestionID: question.ID}
+	no := &models.Answer{Text: "no", Enabled: true, QuestionID: question.ID}
+	ms.NoError(models.DB.Create(yes))
+	ms.NoError(models.DB.Create(no))
+
+	response := &models.Response{UserID: "someguy", QuestionID: question.ID}
+	ms.NoError(models.DB.Create(response))
+	ms.NoError(models.DB.Create(&models.ResponseAnswer{ResponseID: response.ID, AnswerID: yes.ID}))
+
+	// the user changes their answer, the way ResponsesUpdate does
+	ms.NoError(models.ArchiveResponse(models.DB, response, models.VersionUpdated))
+	ms.NoError(models.DB.RawQuery("DELETE FROM response_answers WHERE response_id = ?", response.ID).Exec())
+	ms.NoError(models.DB.Create(&models.ResponseAnswer{ResponseID: response.ID, AnswerID: no.ID}))
+	response.Version++
+	ms.NoError(models.DB.Update(response))
+
+	// and retracts a response to another question
+	other := fixtures.Question(ms.T(), campaign, "Anything else?", 0, 0)
+	retracted := &models.Response{UserID: "someguy", QuestionID: other.ID, Text: "never mind"}
+	ms.NoError(models.DB.Create(retracted))
+	ms.NoError(models.ArchiveResponse(models.DB, retracted, models.VersionRetracted))
+	ms.NoError(models.DB.Destroy(retracted))
+
+	ms.NoError(models.DB.Create(&models.Response{UserID: "someotherguy", QuestionID: other.ID, Text: "not mine"}))
+
+	history, err := models.ResponseHistory(models.DB, "someguy")
+	ms.NoError(err)
+	ms.Len(history, 1)
+	ms.Equal(response.ID, history[0].ID)
+	ms.Equal(2, history[0].Version)
+	ms.Equal("history", history[0].CampaignName)
+	ms.Equal("Pick one", history[0].QuestionText)
+	ms.Len(history[0].Answers, 1)
+	ms.Equal(no.ID, history[0].Answers[0].ID)
+	ms.Equal("no", history[0].Answers[0].Text)
+}
+
+func (ms *ModelSuite) Test_History_DecryptsText() {
+	defer ms.useTextKeys()()
+
+	campaign := fixtures.Campaign(ms.T(), "history", 0)
+	question := fixtures.Question(ms.T(), campaign, "What would you change?", 0, 0)
+	ms.NoError(models.DB.Create(&models.Response{UserID: "someguy", QuestionID: question.ID, Text: "the colors"}))
+
+	// the history is read with raw SQL, which skips the model's AfterFind
+	var stored string
+	ms.NoError(models.DB.Store.Get(&stored, "SELECT text FROM responses WHERE user_id = ?", "someguy"))
+	ms.Equal("test", models.TextKeys.KeyID(stored))
+
+	history, err := models.ResponseHistory(models.DB, "someguy")
+	ms.NoError(err)
+	ms.Len(history, 1)
+	ms.Equal("the colors", history[0].Text)
+	ms.Empty(history[0].Answers)
+}
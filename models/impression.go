@@ -0,0 +1,105 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Impression records a question being served to a user in their question list
+type Impression struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	UserID     string     `json:"user_id" db:"user_id"`
+	QuestionID uuid.UUID  `json:"question_id" db:"question_id"`
+	CampaignID uuid.UUID  `json:"campaign_id" db:"campaign_id"`
+	VariantID  nulls.UUID `json:"variant_id" db:"variant_id"`
+}
+
+// String is not required by pop and may be deleted
+func (i Impression) String() string {
+	ji, _ := json.Marshal(i)
+	return string(ji)
+}
+
+// Impressions is not required by pop and may be deleted
+type Impressions []Impression
+
+// String is not required by pop and may be deleted
+func (i Impressions) String() string {
+	ji, _ := json.Marshal(i)
+	return string(ji)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (i *Impression) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: i.UserID, Name: "UserID"},
+		&validators.UUIDIsPresent{Field: i.QuestionID, Name: "QuestionID"},
+		&validators.UUIDIsPresent{Field: i.CampaignID, Name: "CampaignID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (i *Impression) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (i *Impression) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ServedSince returns the questions served to the user since the given time, mapped to their campaigns.
+// Impressions for anonymous campaigns are stored under the campaign's respondent ID, so every ID the user
+// goes by is passed in.
+func ServedSince(tx *pop.Connection, respondentIDs []string, since time.Time) (map[uuid.UUID]uuid.UUID, error) {
+	impressions := Impressions{}
+	err := tx.Select("question_id", "campaign_id").Where("user_id IN (?)", stringArgs(respondentIDs)...).Where("created_at >= ?", since).All(&impressions)
+	if err != nil {
+		return nil, err
+	}
+
+	served := map[uuid.UUID]uuid.UUID{}
+	for _, i := range impressions {
+		served[i.QuestionID] = i.CampaignID
+	}
+	return served, nil
+}
+
+// LastActivity returns when the user last answered or dismissed a question in each campaign, looking under
+// every respondent ID the user goes by
+func LastActivity(tx *pop.Connection, respondentIDs []string) (map[uuid.UUID]time.Time, error) {
+	rows := []struct {
+		CampaignID uuid.UUID `db:"campaign_id"`
+		At         time.Time `db:"at"`
+	}{}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(respondentIDs)), ", ")
+	query := `SELECT questions.campaign_id AS campaign_id, MAX(a.at) AS at FROM (
+		SELECT question_id, created_at AS at FROM responses WHERE user_id IN (` + placeholders + `)
+		UNION ALL
+		SELECT question_id, updated_at AS at FROM dismissals WHERE user_id IN (` + placeholders + `)
+	) a JOIN questions ON questions.id = a.question_id GROUP BY questions.campaign_id`
+
+	args := stringArgs(respondentIDs)
+	if err := tx.Store.Select(&rows, query, append(args, args...)...); err != nil {
+		return nil, err
+	}
+
+	last := map[uuid.UUID]time.Time{}
+	for _, r := range rows {
+		last[r.CampaignID] = r.At
+	}
+	return last, nil
+}
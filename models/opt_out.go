@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// NotOptedOutSQL is a where clause fragment on campaigns that leaves out the categories the user opted out of.
+// It takes the user ID and their SuppressionID, which keeps the opt outs of users who were erased.
+const NotOptedOutSQL = "(campaigns.category IS NULL OR campaigns.category NOT IN (SELECT category FROM opt_outs WHERE user_id IN (?, ?)))"
+
+// OptOut is a user asking not to be asked questions.  An empty category opts the user out of every
+// campaign, otherwise only campaigns in that category are left out.
+type OptOut struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	Category   string    `json:"category" db:"category"`
+	QuestionID uuid.UUID `json:"question_id,omitempty" db:"-"`
+}
+
+// String is not required by pop and may be deleted
+func (o OptOut) String() string {
+	jo, _ := json.Marshal(o)
+	return string(jo)
+}
+
+// OptOuts is not required by pop and may be deleted
+type OptOuts []OptOut
+
+// String is not required by pop and may be deleted
+func (o OptOuts) String() string {
+	jo, _ := json.Marshal(o)
+	return string(jo)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (o *OptOut) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: o.UserID, Name: "UserID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (o *OptOut) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (o *OptOut) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// OptOutUser opts the user out of the category, or everything for an empty category, and returns true if
+// the opt out is new.  Opting out again is a no-op.
+func OptOutUser(tx *pop.Connection, userID, category string) (bool, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	added, err := tx.RawQuery("INSERT IGNORE INTO opt_outs (id, user_id, category, created_at, updated_at) VALUES (?, ?, ?, ?, ?)", id, userID, strings.TrimSpace(category), now, now).ExecWithCount()
+	if err != nil {
+		return false, err
+	}
+	return added > 0, nil
+}
+
+// OptedOutOfEverything returns true if the user has opted out of all campaigns, including before they were erased
+func OptedOutOfEverything(tx *pop.Connection, userID string) (bool, error) {
+	return tx.Where("user_id IN (?, ?)", userID, SuppressionID(userID)).Where("category = ''").Exists(&OptOut{})
+}
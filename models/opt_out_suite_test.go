@@ -0,0 +1,18 @@
+package models_test
+
+import "github.com/YaleSpinup/tweaser/models"
+
+func (ms *ModelSuite) Test_OptOut_Duplicates() {
+	added, err := models.OptOutUser(models.DB, "someguy", "research")
+	ms.NoError(err)
+	ms.True(added)
+
+	// opting out again, with the category padded, doesn't add anything
+	added, err = models.OptOutUser(models.DB, "someguy", " research ")
+	ms.NoError(err)
+	ms.False(added)
+
+	added, err = models.OptOutUser(models.DB, "someguy", "")
+	ms.NoError(err)
+	ms.True(added)
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// FrequencyPolicy limits how many questions are put in front of a user and how soon after
+// answering or dismissing a question they see more.  Zero values mean no limit.
+type FrequencyPolicy struct {
+	MaxPerDay  int
+	MaxPerWeek int
+	Cooldown   time.Duration
+}
+
+// Policy returns the frequency policy configured on the campaign
+func (c *Campaign) Policy() FrequencyPolicy {
+	p := FrequencyPolicy{}
+	if c.MaxPerDay.Valid {
+		p.MaxPerDay = c.MaxPerDay.Int
+	}
+	if c.MaxPerWeek.Valid {
+		p.MaxPerWeek = c.MaxPerWeek.Int
+	}
+	if c.CooldownSeconds.Valid {
+		p.Cooldown = time.Duration(c.CooldownSeconds.Int) * time.Second
+	}
+	return p
+}
+
+// CoolingDown returns true if the user's last answer or dismissal at last is still inside the cooldown
+func (p FrequencyPolicy) CoolingDown(last, now time.Time) bool {
+	return p.Cooldown > 0 && !last.IsZero() && now.Sub(last) < p.Cooldown
+}
+
+// Allow returns the candidate questions, in order, that fit inside the daily and weekly caps.  The
+// servedDay and servedWeek sets hold the questions already served to the user in the last day and
+// week.  Serving one of those again doesn't use up any more of the caps.
+func (p FrequencyPolicy) Allow(candidates []uuid.UUID, servedDay, servedWeek map[uuid.UUID]bool) []uuid.UUID {
+	dayLeft, weekLeft := p.MaxPerDay-len(servedDay), p.MaxPerWeek-len(servedWeek)
+
+	allowed := []uuid.UUID{}
+	for _, id := range candidates {
+		newToday, newThisWeek := !servedDay[id], !servedWeek[id]
+		if (p.MaxPerDay > 0 && newToday && dayLeft <= 0) || (p.MaxPerWeek > 0 && newThisWeek && weekLeft <= 0) {
+			continue
+		}
+
+		if newToday {
+			dayLeft--
+		}
+		if newThisWeek {
+			weekLeft--
+		}
+		allowed = append(allowed, id)
+	}
+	return allowed
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func Test_FrequencyPolicyAllow(t *testing.T) {
+	a, b, c, d := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	candidates := []uuid.UUID{a, b, c, d}
+
+	tests := []struct {
+		name       string
+		policy     FrequencyPolicy
+		servedDay  map[uuid.UUID]bool
+		servedWeek map[uuid.UUID]bool
+		expected   []uuid.UUID
+	}{
+		{
+			name:     "no limits",
+			policy:   FrequencyPolicy{},
+			expected: []uuid.UUID{a, b, c, d},
+		},
+		{
+			name:     "daily cap with nothing served",
+			policy:   FrequencyPolicy{MaxPerDay: 2},
+			expected: []uuid.UUID{a, b},
+		},
+		{
+			name:      "questions served today don't count twice",
+			policy:    FrequencyPolicy{MaxPerDay: 2},
+			servedDay: map[uuid.UUID]bool{c: true},
+			expected:  []uuid.UUID{a, c},
+		},
+		{
+			name:       "weekly cap used up",
+			policy:     FrequencyPolicy{MaxPerDay: 5, MaxPerWeek: 2},
+			servedWeek: map[uuid.UUID]bool{b: true, uuid.Must(uuid.NewV4()): true},
+			expected:   []uuid.UUID{b},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.servedDay == nil {
+				tt.servedDay = map[uuid.UUID]bool{}
+			}
+			if tt.servedWeek == nil {
+				tt.servedWeek = map[uuid.UUID]bool{}
+			}
+
+			got := tt.policy.Allow(candidates, tt.servedDay, tt.servedWeek)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_FrequencyPolicyCoolingDown(t *testing.T) {
+	now := time.Now()
+	p := FrequencyPolicy{Cooldown: time.Hour}
+
+	if !p.CoolingDown(now.Add(-30*time.Minute), now) {
+		t.Error("expected activity 30 minutes ago to be cooling down")
+	}
+
+	if p.CoolingDown(now.Add(-2*time.Hour), now) {
+		t.Error("expected activity 2 hours ago not to be cooling down")
+	}
+
+	if p.CoolingDown(time.Time{}, now) {
+		t.Error("expected no activity not to be cooling down")
+	}
+
+	if (FrequencyPolicy{}).CoolingDown(now, now) {
+		t.Error("expected no cooldown not to be cooling down")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
@@ -11,16 +12,23 @@ import (
 )
 
 type Question struct {
-	ID         uuid.UUID `json:"id" db:"id"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
-	Text       string    `json:"text" db:"text"`
-	Campaign   Campaign  `belongs_to:"campaign" json:"-"`
-	CampaignID uuid.UUID `json:"campaign_id" db:"campaign_id"`
-	Enabled    bool      `json:"enabled" db:"enabled"`
-	Answers    Answers   `has_many:"answers" json:"answers,omitempty"`
-	Type       string    `json:"type" db:"type"`
-	Token      string    `json:"token,omitempty" db:"-"`
+	ID           uuid.UUID  `json:"id" db:"id"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	Text         string     `json:"text" db:"text"`
+	Campaign     Campaign   `belongs_to:"campaign" json:"-"`
+	CampaignID   uuid.UUID  `json:"campaign_id" db:"campaign_id"`
+	SectionID    nulls.UUID `json:"section_id" db:"section_id"`
+	Enabled      bool       `json:"enabled" db:"enabled"`
+	Answers      Answers    `has_many:"answers" json:"answers,omitempty"`
+	Type         string     `json:"type" db:"type"`
+	Required     bool       `json:"required" db:"required"`
+	MaxResponses nulls.Int  `json:"max_responses" db:"max_responses"`
+	Priority     int        `json:"priority" db:"priority"`
+	Position     int        `json:"position" db:"position"`
+	VariantID    *uuid.UUID `json:"variant_id,omitempty" db:"-"`
+	Token        string     `json:"token,omitempty" db:"-"`
+	Draft        *string    `json:"draft,omitempty" db:"-"`
 }
 
 // String is not required by pop and may be deleted
@@ -43,6 +51,8 @@ func (q Questions) String() string {
 func (q *Question) Validate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.Validate(
 		&validators.StringIsPresent{Field: q.Text, Name: "Text"},
+		&QuotaIsPositive{Name: "MaxResponses", Field: q.MaxResponses},
+		&SectionInCampaign{Name: "SectionID", SectionID: q.SectionID, CampaignID: q.CampaignID, tx: tx},
 	), nil
 }
 
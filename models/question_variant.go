@@ -0,0 +1,232 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// QuestionVariant is an alternate wording of a question, used to test which wording performs better
+type QuestionVariant struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	Text       string    `json:"text" db:"text"`
+	Enabled    bool      `json:"enabled" db:"enabled"`
+	Question   Question  `belongs_to:"question" json:"-"`
+	QuestionID uuid.UUID `json:"question_id" db:"question_id"`
+}
+
+// String is not required by pop and may be deleted
+func (q QuestionVariant) String() string {
+	jq, _ := json.Marshal(q)
+	return string(jq)
+}
+
+// QuestionVariants is not required by pop and may be deleted
+type QuestionVariants []QuestionVariant
+
+// String is not required by pop and may be deleted
+func (q QuestionVariants) String() string {
+	jq, _ := json.Marshal(q)
+	return string(jq)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (q *QuestionVariant) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: q.Text, Name: "Text"},
+		&validators.UUIDIsPresent{Field: q.QuestionID, Name: "QuestionID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (q *QuestionVariant) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (q *QuestionVariant) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// PickVariant deterministically assigns the user one of the question's variants, or nil for the
+// question's own text.  The question's own text is the control and is picked as often as each variant.
+func PickVariant(questionID uuid.UUID, userID string, variants QuestionVariants) *QuestionVariant {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(questionID.String() + ":" + userID))
+	arm := binary.BigEndian.Uint64(sum[:8]) % uint64(len(variants)+1)
+	if arm == 0 {
+		return nil
+	}
+	return &variants[arm-1]
+}
+
+// AssignVariant returns the variant the user was assigned for the question, or nil for the question's own
+// text.  The first time a question is served to a user, one of its enabled variants is picked and the
+// assignment is stored, so enabling or disabling variants later only changes what new users are assigned.
+// A user whose variant was disabled since is served the question's own text until it is enabled again.
+// Pass the campaign's respondent ID, so anonymous campaigns only store the hash.
+func AssignVariant(tx *pop.Connection, questionID uuid.UUID, userID string) (*QuestionVariant, error) {
+	assignment, err := FindVariantAssignment(tx, questionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if assignment == nil {
+		variants := QuestionVariants{}
+		if err := tx.Where("question_id = ?", questionID).Where("enabled = true").Order("created_at, id").All(&variants); err != nil {
+			return nil, err
+		}
+
+		// questions that aren't being A/B tested don't need an assignment
+		if len(variants) == 0 {
+			return nil, nil
+		}
+
+		picked := nulls.UUID{}
+		if v := PickVariant(questionID, userID, variants); v != nil {
+			picked = nulls.NewUUID(v.ID)
+		}
+
+		id, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+
+		// a concurrent request may have stored an assignment first, in which case that one is kept
+		now := time.Now()
+		err = tx.RawQuery("INSERT IGNORE INTO variant_assignments (id, question_id, user_id, variant_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+			id, questionID, userID, picked, now, now).Exec()
+		if err != nil {
+			return nil, err
+		}
+
+		if assignment, err = FindVariantAssignment(tx, questionID, userID); err != nil || assignment == nil {
+			return nil, err
+		}
+	}
+
+	if !assignment.VariantID.Valid {
+		return nil, nil
+	}
+
+	variant := &QuestionVariant{}
+	if err := tx.Find(variant, assignment.VariantID.UUID); err != nil {
+		return nil, err
+	}
+
+	if !variant.Enabled {
+		return nil, nil
+	}
+	return variant, nil
+}
+
+// VariantBelongsToQuestion is a custom validator for the variant a response was served
+type VariantBelongsToQuestion struct {
+	Name       string
+	VariantID  nulls.UUID
+	QuestionID uuid.UUID
+	tx         *pop.Connection
+}
+
+// IsValid validates that the variant, when set, is a variant of the question being responded to
+func (v *VariantBelongsToQuestion) IsValid(errors *validate.Errors) {
+	if !v.VariantID.Valid {
+		return
+	}
+
+	variant := &QuestionVariant{}
+	if err := v.tx.Find(variant, v.VariantID.UUID); err != nil || variant.QuestionID != v.QuestionID {
+		errors.Add(validators.GenerateKey(v.Name), fmt.Sprintf("Variant %s is not a variant of question %s", v.VariantID.UUID, v.QuestionID))
+	}
+}
+
+// VariantStats is how one wording of a question performed.  Served is the number of users the wording
+// was shown to and Count is the number of times each answer was chosen with it.
+type VariantStats struct {
+	VariantID    nulls.UUID     `json:"variant_id"`
+	Text         string         `json:"text"`
+	Served       int            `json:"served"`
+	Responses    int            `json:"responses"`
+	ResponseRate float64        `json:"response_rate"`
+	Count        map[string]int `json:"count"`
+	Suppressed   []string       `json:"suppressed,omitempty"`
+}
+
+// Suppress hides the counts small enough to single out a respondent, for anonymous campaigns.  The
+// response rate is hidden along with them, since it could be used to work them out.
+func (s *VariantStats) Suppress(min int) {
+	var answers []string
+	s.Suppressed = SuppressSmallFields(map[string]*int{"served": &s.Served, "responses": &s.Responses}, min)
+	if len(s.Suppressed) > 0 {
+		s.ResponseRate = 0
+	}
+	s.Count, answers = SuppressSmallCounts(s.Count, min)
+	s.Suppressed = append(s.Suppressed, answers...)
+}
+
+// VariantReport breaks down the responses to a question by the wording users were served, starting
+// with the question's own text.  Questions that were never A/B tested have an empty report.
+func VariantReport(tx *pop.Connection, question *Question) ([]VariantStats, error) {
+	variants := QuestionVariants{}
+	if err := tx.Where("question_id = ?", question.ID).Order("created_at, id").All(&variants); err != nil {
+		return nil, err
+	}
+
+	if len(variants) == 0 {
+		return []VariantStats{}, nil
+	}
+
+	report := []VariantStats{{Text: question.Text}}
+	for _, v := range variants {
+		report = append(report, VariantStats{VariantID: nulls.NewUUID(v.ID), Text: v.Text})
+	}
+
+	for i, stats := range report {
+		if err := tx.Store.Get(&report[i].Served, "SELECT COUNT(DISTINCT user_id) FROM impressions WHERE question_id = ? AND variant_id <=> ?", question.ID, stats.VariantID); err != nil {
+			return nil, err
+		}
+
+		if err := tx.Store.Get(&report[i].Responses, "SELECT COUNT(*) FROM responses WHERE question_id = ? AND variant_id <=> ?", question.ID, stats.VariantID); err != nil {
+			return nil, err
+		}
+
+		if report[i].Served > 0 {
+			report[i].ResponseRate = float64(report[i].Responses) / float64(report[i].Served)
+		}
+
+		rows := []struct {
+			AnswerID uuid.UUID `db:"answer_id"`
+			Count    int       `db:"count"`
+		}{}
+
+		query := `SELECT response_answers.answer_id AS answer_id, COUNT(*) AS count FROM response_answers
+			JOIN responses ON responses.id = response_answers.response_id
+			WHERE responses.question_id = ? AND responses.variant_id <=> ? GROUP BY response_answers.answer_id`
+		if err := tx.Store.Select(&rows, query, question.ID, stats.VariantID); err != nil {
+			return nil, err
+		}
+
+		report[i].Count = map[string]int{}
+		for _, r := range rows {
+			report[i].Count[r.AnswerID.String()] = r.Count
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,48 @@
+package models_test
+
+import (
+	"github.com/YaleSpinup/tweaser/fixtures"
+	"github.com/YaleSpinup/tweaser/models"
+)
+
+func (ms *ModelSuite) Test_QuestionVariant_AssignmentSticks() {
+	campaign := fixtures.Campaign(ms.T(), "variants", 0)
+	question := fixtures.Question(ms.T(), campaign, "What should we build next?", 0, 0)
+
+	variants := []*models.QuestionVariant{
+		{QuestionID: question.ID, Text: "Which feature would help you most?", Enabled: true},
+		{QuestionID: question.ID, Text: "What would make your day easier?", Enabled: true},
+	}
+	for _, v := range variants {
+		ms.NoError(models.DB.Create(v))
+	}
+
+	assigned := map[string]*models.QuestionVariant{}
+	for _, u := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"} {
+		v, err := models.AssignVariant(models.DB, question.ID, u)
+		ms.NoError(err)
+		assigned[u] = v
+	}
+
+	// disabling the first variant would shift the hash, but users keep what they were assigned
+	variants[0].Enabled = false
+	ms.NoError(models.DB.Update(variants[0]))
+
+	for u, before := range assigned {
+		after, err := models.AssignVariant(models.DB, question.ID, u)
+		ms.NoError(err)
+		if before == nil || before.ID == variants[1].ID {
+			ms.Equal(before == nil, after == nil, "user %s changed arms", u)
+			if before != nil {
+				ms.Equal(before.ID, after.ID)
+			}
+			continue
+		}
+
+		// users of the disabled variant see the question's own text without being reassigned
+		ms.Nil(after)
+		assignment, err := models.FindVariantAssignment(models.DB, question.ID, u)
+		ms.NoError(err)
+		ms.Equal(variants[0].ID, assignment.VariantID.UUID)
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func Test_QuestionVariant(t *testing.T) {
+	t.Log("This test needs to be implemented!")
+}
+
+func Test_PickVariant(t *testing.T) {
+	questionID := uuid.Must(uuid.NewV4())
+
+	if v := PickVariant(questionID, "someguy", QuestionVariants{}); v != nil {
+		t.Errorf("expected no variant for a question without variants, got %s", v)
+	}
+
+	variants := QuestionVariants{
+		{ID: uuid.Must(uuid.NewV4()), Text: "Which feature would help you most?"},
+		{ID: uuid.Must(uuid.NewV4()), Text: "What should we build next?"},
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		user := fmt.Sprintf("someuser%d", i)
+
+		first := PickVariant(questionID, user, variants)
+		if second := PickVariant(questionID, user, variants); first != second {
+			t.Fatalf("expected %s to get the same variant every time", user)
+		}
+
+		if first == nil {
+			counts["control"]++
+		} else {
+			counts[first.ID.String()]++
+		}
+	}
+
+	if len(counts) != 3 {
+		t.Fatalf("expected users spread over the control and 2 variants, got %v", counts)
+	}
+
+	for arm, count := range counts {
+		if count < 850 || count > 1150 {
+			t.Errorf("expected about 1000 users for %s, got %d", arm, count)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// QuestionHasRoomSQL is a where clause fragment that excludes questions which have reached their quota
+	QuestionHasRoomSQL = "(questions.max_responses IS NULL OR questions.max_responses > (SELECT COUNT(*) FROM responses WHERE responses.question_id = questions.id))"
+
+	// AnswerHasRoomSQL is a where clause fragment that excludes answers which have reached their quota
+	AnswerHasRoomSQL = "(answers.max_responses IS NULL OR answers.max_responses > (SELECT COUNT(*) FROM response_answers WHERE response_answers.answer_id = answers.id))"
+)
+
+// QuotaIsPositive is a custom validator for optional max_responses quotas
+type QuotaIsPositive struct {
+	Name  string
+	Field nulls.Int
+}
+
+// IsValid validates that a quota, when set, allows at least one response
+func (v *QuotaIsPositive) IsValid(errors *validate.Errors) {
+	if v.Field.Valid && v.Field.Int < 1 {
+		errors.Add(validators.GenerateKey(v.Name), fmt.Sprintf("%s must be greater than 0 when set.", v.Name))
+	}
+}
+
+// ResponseAllowed is a custom validator that checks a new response isn't a duplicate and fits in the
+// campaign, question and answer quotas.  It takes a row lock on the campaign first and then runs every check
+// one after another with locking reads, so that concurrent responses to the same campaign are checked one at
+// a time, which keeps users from answering twice and the quotas from being overshot.  The lock is held until
+// the surrounding transaction ends.  The checks share one validator because validate runs validators
+// concurrently, and a transaction's connection can only run one query at a time.  When ResponseID is set,
+// an existing response is being edited and only its answers are checked, not counting the answers it
+// already selects.
+type ResponseAllowed struct {
+	Name       string
+	UserID     string
+	QuestionID uuid.UUID
+	RunID      nulls.UUID
+	AnswerIDs  []uuid.UUID
+	ResponseID uuid.UUID
+	tx         *pop.Connection
+}
+
+// IsValid validates that the user hasn't already responded and that there is room left in every quota the
+// response counts against
+func (v *ResponseAllowed) IsValid(errors *validate.Errors) {
+	key := validators.GenerateKey(v.Name)
+
+	campaign := Campaign{}
+	query := v.tx.RawQuery("SELECT * FROM campaigns WHERE id = (SELECT campaign_id FROM questions WHERE id = ?) FOR UPDATE", v.QuestionID)
+	if err := query.First(&campaign); err != nil {
+		errors.Add(key, fmt.Sprintf("Campaign not found for question %s.", v.QuestionID))
+		return
+	}
+
+	if v.ResponseID != uuid.Nil {
+		v.answersHaveRoom(errors, key)
+		return
+	}
+
+	// each run of a recurring campaign takes a fresh response
+	duplicates := "SELECT COUNT(*) FROM responses WHERE question_id = ? AND user_id = ?"
+	args := []interface{}{v.QuestionID, v.UserID}
+	if v.RunID.Valid {
+		duplicates += " AND run_id = ?"
+		args = append(args, v.RunID)
+	}
+
+	count, err := countLocked(v.tx, duplicates, args...)
+	if err != nil {
+		errors.Add(key, "Unable to count responses.")
+		return
+	}
+
+	if count > 0 {
+		errors.Add(validators.GenerateKey("UserAlreadyResponded"), fmt.Sprintf("User %s has already responded to question %s.", v.UserID, v.QuestionID))
+		return
+	}
+
+	// a user answering another question in the campaign doesn't count as a new respondent, so users
+	// who started the campaign before it filled up can finish it
+	respondent, err := respondedToCampaign(v.tx, campaign.ID, v.UserID)
+	if err != nil {
+		errors.Add(key, "Unable to count campaign responses.")
+		return
+	}
+
+	if campaign.ClosedAt.Valid && !respondent {
+		errors.Add(key, fmt.Sprintf("Campaign %s is closed.", campaign.ID))
+		return
+	}
+
+	if campaign.MaxResponses.Valid && !respondent {
+		count, err := countLocked(v.tx, "SELECT COUNT(DISTINCT user_id) FROM responses WHERE question_id IN (SELECT id FROM questions WHERE campaign_id = ?)", campaign.ID)
+		if err != nil {
+			errors.Add(key, "Unable to count campaign responses.")
+			return
+		}
+
+		if count >= campaign.MaxResponses.Int {
+			errors.Add(key, fmt.Sprintf("Campaign %s has reached its response quota.", campaign.ID))
+			return
+		}
+	}
+
+	question := Question{}
+	if err := v.tx.Find(&question, v.QuestionID); err != nil {
+		errors.Add(key, fmt.Sprintf("Question %s not found.", v.QuestionID))
+		return
+	}
+
+	if question.MaxResponses.Valid {
+		count, err := countLocked(v.tx, "SELECT COUNT(*) FROM responses WHERE question_id = ?", question.ID)
+		if err != nil {
+			errors.Add(key, "Unable to count question responses.")
+			return
+		}
+
+		if count >= question.MaxResponses.Int {
+			errors.Add(key, fmt.Sprintf("Question %s has reached its response quota.", question.ID))
+			return
+		}
+	}
+
+	v.answersHaveRoom(errors, key)
+}
+
+// answersHaveRoom validates that there is room left in the quotas of the selected answers
+func (v *ResponseAllowed) answersHaveRoom(errors *validate.Errors, key string) {
+	for _, id := range v.AnswerIDs {
+		answer := Answer{}
+		if err := v.tx.Find(&answer, id); err != nil || !answer.MaxResponses.Valid {
+			continue
+		}
+
+		count, err := countLocked(v.tx, "SELECT COUNT(*) FROM response_answers WHERE answer_id = ? AND response_id <> ?", answer.ID, v.ResponseID)
+		if err != nil {
+			errors.Add(key, "Unable to count answer responses.")
+			return
+		}
+
+		if count >= answer.MaxResponses.Int {
+			errors.Add(key, fmt.Sprintf("Answer %s has reached its response quota.", answer.ID))
+		}
+	}
+}
+
+// CloseCampaignIfFull marks the campaign closed once it has reached its response quota
+func CloseCampaignIfFull(tx *pop.Connection, campaignID uuid.UUID) error {
+	campaign := Campaign{}
+	if err := tx.Find(&campaign, campaignID); err != nil {
+		return err
+	}
+
+	if !campaign.MaxResponses.Valid || campaign.ClosedAt.Valid {
+		return nil
+	}
+
+	count, err := countLocked(tx, "SELECT COUNT(DISTINCT user_id) FROM responses WHERE question_id IN (SELECT id FROM questions WHERE campaign_id = ?)", campaign.ID)
+	if err != nil {
+		return err
+	}
+
+	if count < campaign.MaxResponses.Int {
+		return nil
+	}
+
+	return tx.RawQuery("UPDATE campaigns SET closed_at = ? WHERE id = ? AND closed_at IS NULL", time.Now(), campaign.ID).Exec()
+}
+
+// respondedToCampaign returns true if the user has already responded to a question in the campaign
+func respondedToCampaign(tx *pop.Connection, campaignID uuid.UUID, userID string) (bool, error) {
+	count, err := countLocked(tx, "SELECT COUNT(*) FROM responses WHERE question_id IN (SELECT id FROM questions WHERE campaign_id = ?) AND user_id = ?", campaignID, userID)
+	return count > 0, err
+}
+
+// countLocked runs a count query as a locking read.  Plain reads in a repeatable read transaction
+// come from the snapshot taken at the first read, so they could miss responses committed while we
+// were waiting for the campaign lock.
+func countLocked(tx *pop.Connection, query string, args ...interface{}) (int, error) {
+	var count int
+	if err := tx.Store.Get(&count, query+" LOCK IN SHARE MODE", args...); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,39 @@
+package models_test
+
+import (
+	"github.com/YaleSpinup/tweaser/fixtures"
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/nulls"
+)
+
+func (ms *ModelSuite) Test_Quota_Duplicates() {
+	campaign := fixtures.Campaign(ms.T(), "duplicates", 0)
+	question := fixtures.Question(ms.T(), campaign, "once", 0, 0)
+
+	verrs, err := models.DB.ValidateAndCreate(&models.Response{UserID: "someguy", QuestionID: question.ID, Text: "first"})
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	verrs, err = models.DB.ValidateAndCreate(&models.Response{UserID: "someguy", QuestionID: question.ID, Text: "again"})
+	ms.NoError(err)
+	ms.Len(verrs.Get("user_already_responded"), 1)
+	ms.Empty(verrs.Get("quota_not_reached"))
+
+	// the unique index backs up the check for a response that skips validation
+	ms.Error(models.DB.Create(&models.Response{UserID: "someguy", QuestionID: question.ID, Text: "racing"}))
+}
+
+func (ms *ModelSuite) Test_Quota_QuestionFull() {
+	campaign := fixtures.Campaign(ms.T(), "full", 0)
+	question := fixtures.Question(ms.T(), campaign, "only one", 0, 0)
+	question.MaxResponses = nulls.NewInt(1)
+	ms.NoError(models.DB.Update(question))
+
+	verrs, err := models.DB.ValidateAndCreate(&models.Response{UserID: "someguy", QuestionID: question.ID, Text: "first"})
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	verrs, err = models.DB.ValidateAndCreate(&models.Response{UserID: "someotherguy", QuestionID: question.ID, Text: "second"})
+	ms.NoError(err)
+	ms.Len(verrs.Get("quota_not_reached"), 1)
+}
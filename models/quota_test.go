@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/validate/v3"
+)
+
+func Test_QuotaIsPositive(t *testing.T) {
+	tests := []struct {
+		quota nulls.Int
+		valid bool
+	}{
+		{nulls.Int{}, true},
+		{nulls.NewInt(1), true},
+		{nulls.NewInt(500), true},
+		{nulls.NewInt(0), false},
+		{nulls.NewInt(-3), false},
+	}
+
+	for _, tt := range tests {
+		errs := validate.Validate(&QuotaIsPositive{Name: "MaxResponses", Field: tt.quota})
+		if errs.HasAny() == tt.valid {
+			t.Errorf("expected quota %+v valid=%t, got errors %v", tt.quota, tt.valid, errs)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/YaleSpinup/tweaser/ratelimit"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+)
+
+// RateLimitStore keeps rate limit buckets in the rate_limits table, so replicas of the app share them.
+// Each bucket is updated in its own transaction, so it isn't rolled back along with a refused request.
+type RateLimitStore struct {
+	DB *pop.Connection
+}
+
+// Take takes a token from the key's bucket, locking its row while the bucket is refilled
+func (s RateLimitStore) Take(key string, p ratelimit.Policy, now time.Time) (bool, time.Duration, error) {
+	var allowed bool
+	var wait time.Duration
+	err := s.DB.Transaction(func(tx *pop.Connection) error {
+		if err := tx.RawQuery("INSERT IGNORE INTO rate_limits (bucket, tokens, refilled_at) VALUES (?, 0, NULL)", key).Exec(); err != nil {
+			return err
+		}
+
+		row := struct {
+			Tokens     float64    `db:"tokens"`
+			RefilledAt nulls.Time `db:"refilled_at"`
+		}{}
+		if err := tx.Store.Get(&row, "SELECT tokens, refilled_at FROM rate_limits WHERE bucket = ? FOR UPDATE", key); err != nil {
+			return err
+		}
+
+		b := ratelimit.Bucket{Tokens: row.Tokens}
+		if row.RefilledAt.Valid {
+			b.Last = row.RefilledAt.Time
+		}
+
+		b, allowed, wait = p.Take(b, now.UTC())
+		if !allowed {
+			return nil
+		}
+
+		return tx.RawQuery("UPDATE rate_limits SET tokens = ?, refilled_at = ? WHERE bucket = ?", b.Tokens, b.Last, key).Exec()
+	})
+
+	return allowed, wait, err
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence is the subset of an iCalendar RRULE used to repeat a campaign, e.g. FREQ=MONTHLY;INTERVAL=3
+// for every quarter.  COUNT optionally limits the number of runs.
+type Recurrence struct {
+	Freq     string
+	Interval int
+	Count    int
+}
+
+// ParseRecurrence parses a recurrence rule like FREQ=WEEKLY;INTERVAL=2;COUNT=6
+func ParseRecurrence(rule string) (Recurrence, error) {
+	rec := Recurrence{Interval: 1}
+
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	for _, part := range strings.Split(rule, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return rec, fmt.Errorf("recurrence part %q should look like NAME=VALUE", part)
+		}
+
+		name, value := strings.ToUpper(strings.TrimSpace(kv[0])), strings.ToUpper(strings.TrimSpace(kv[1]))
+		switch name {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rec.Freq = value
+			default:
+				return rec, fmt.Errorf("recurrence FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY, not %s", value)
+			}
+		case "INTERVAL", "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return rec, fmt.Errorf("recurrence %s must be a positive number, not %s", name, value)
+			}
+
+			if name == "INTERVAL" {
+				rec.Interval = n
+			} else {
+				rec.Count = n
+			}
+		default:
+			return rec, fmt.Errorf("recurrence %s is not supported", name)
+		}
+	}
+
+	if rec.Freq == "" {
+		return rec, fmt.Errorf("recurrence is missing FREQ")
+	}
+
+	return rec, nil
+}
+
+// Start returns the start of the nth run (counting from 0) of a series that starts at first.  Months
+// and years are added to the first start, so a run on the 31st falls back to the end of shorter months.
+func (r Recurrence) Start(first time.Time, n int) time.Time {
+	steps := n * r.Interval
+	switch r.Freq {
+	case "DAILY":
+		return first.AddDate(0, 0, steps)
+	case "WEEKLY":
+		return first.AddDate(0, 0, 7*steps)
+	case "MONTHLY":
+		return addMonths(first, steps)
+	default:
+		return addMonths(first, 12*steps)
+	}
+}
+
+// Period returns the shortest time between two runs
+func (r Recurrence) Period() time.Duration {
+	switch r.Freq {
+	case "DAILY":
+		return time.Duration(r.Interval) * 24 * time.Hour
+	case "WEEKLY":
+		return time.Duration(r.Interval) * 7 * 24 * time.Hour
+	case "MONTHLY":
+		return time.Duration(r.Interval) * 28 * 24 * time.Hour
+	default:
+		return time.Duration(r.Interval) * 365 * 24 * time.Hour
+	}
+}
+
+// addMonths adds months to t, clamping the day to the last day of the resulting month
+func addMonths(t time.Time, months int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected Recurrence
+	}{
+		{"FREQ=MONTHLY;INTERVAL=3", Recurrence{Freq: "MONTHLY", Interval: 3}},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=6", Recurrence{Freq: "WEEKLY", Interval: 2, Count: 6}},
+		{"freq=daily;", Recurrence{Freq: "DAILY", Interval: 1}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRecurrence(tt.rule)
+		if err != nil {
+			t.Errorf("unexpected error parsing %s: %s", tt.rule, err)
+			continue
+		}
+
+		if got != tt.expected {
+			t.Errorf("expected %s to parse to %+v, got %+v", tt.rule, tt.expected, got)
+		}
+	}
+
+	for _, rule := range []string{"", "INTERVAL=2", "FREQ=HOURLY", "FREQ=WEEKLY;INTERVAL=0", "FREQ=WEEKLY;BYDAY=MO", "FREQ"} {
+		if _, err := ParseRecurrence(rule); err == nil {
+			t.Errorf("expected an error parsing %q", rule)
+		}
+	}
+}
+
+func Test_RecurrenceStart(t *testing.T) {
+	first := time.Date(2026, time.January, 31, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		rec      Recurrence
+		n        int
+		expected time.Time
+	}{
+		{Recurrence{Freq: "DAILY", Interval: 1}, 3, time.Date(2026, time.February, 3, 9, 0, 0, 0, time.UTC)},
+		{Recurrence{Freq: "WEEKLY", Interval: 2}, 1, time.Date(2026, time.February, 14, 9, 0, 0, 0, time.UTC)},
+		{Recurrence{Freq: "MONTHLY", Interval: 1}, 1, time.Date(2026, time.February, 28, 9, 0, 0, 0, time.UTC)},
+		{Recurrence{Freq: "MONTHLY", Interval: 3}, 1, time.Date(2026, time.April, 30, 9, 0, 0, 0, time.UTC)},
+		{Recurrence{Freq: "MONTHLY", Interval: 3}, 2, time.Date(2026, time.July, 31, 9, 0, 0, 0, time.UTC)},
+		{Recurrence{Freq: "YEARLY", Interval: 1}, 1, time.Date(2027, time.January, 31, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rec.Start(first, tt.n); !got.Equal(tt.expected) {
+			t.Errorf("expected run %d of %+v to start %s, got %s", tt.n, tt.rec, tt.expected, got)
+		}
+	}
+}
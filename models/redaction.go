@@ -0,0 +1,130 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/YaleSpinup/tweaser/redaction"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// RedactionCounts is the number of personal details of each kind redacted from a response
+type RedactionCounts map[string]int
+
+// Value stores the counts as JSON, or NULL when nothing was redacted
+func (r RedactionCounts) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return nil, nil
+	}
+
+	jr, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(jr), nil
+}
+
+// Scan reads counts stored as JSON
+func (r *RedactionCounts) Scan(src interface{}) error {
+	*r = nil
+
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("can't scan %T into RedactionCounts", src)
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, r)
+}
+
+// Redaction is the campaign's redaction pipeline, the built in detectors when RedactPII is set followed by
+// its own patterns.  It is empty when the campaign doesn't redact responses.
+func (c *Campaign) Redaction() (redaction.Pipeline, error) {
+	p := redaction.Pipeline{}
+	if c.RedactPII {
+		p = append(p, redaction.Builtin()...)
+	}
+
+	patterns, err := redaction.ParsePatterns(c.RedactionPatterns.String)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(p, patterns...), nil
+}
+
+// redact runs the response text through its campaign's redaction pipeline, keeping the redacted text and
+// counts for reports.  When the campaign drops the original text, only the redacted text is kept.
+func (r *Response) redact(tx *pop.Connection) error {
+	r.RedactedText = nulls.String{}
+	r.Redactions = nil
+	if r.Text == "" {
+		return nil
+	}
+
+	campaign, err := CampaignForQuestion(tx, r.QuestionID)
+	if err != nil {
+		return err
+	}
+
+	pipeline, err := campaign.Redaction()
+	if err != nil {
+		return err
+	}
+
+	if len(pipeline) == 0 {
+		return nil
+	}
+
+	text, counts := pipeline.Redact(r.Text)
+	r.RedactedText = nulls.NewString(text)
+	r.Redactions = counts
+	if campaign.DropOriginalText {
+		r.Text = text
+	}
+
+	return nil
+}
+
+// RedactionReport totals the redactions made in the responses to a question
+func RedactionReport(tx *pop.Connection, questionID uuid.UUID) (map[string]int, error) {
+	rows := []RedactionCounts{}
+	if err := tx.Store.Select(&rows, "SELECT redactions FROM responses WHERE question_id = ? AND redactions IS NOT NULL", questionID); err != nil {
+		return nil, err
+	}
+
+	totals := map[string]int{}
+	for _, row := range rows {
+		for name, n := range row {
+			totals[name] += n
+		}
+	}
+	return totals, nil
+}
+
+// RedactionPatternsCompile is a custom validator for a campaign's redaction patterns
+type RedactionPatternsCompile struct {
+	Name     string
+	Patterns nulls.String
+}
+
+// IsValid validates that each of the redaction patterns compiles
+func (v *RedactionPatternsCompile) IsValid(errors *validate.Errors) {
+	if _, err := redaction.ParsePatterns(v.Patterns.String); err != nil {
+		errors.Add(validators.GenerateKey(v.Name), fmt.Sprintf("%s is invalid: %s", v.Name, err))
+	}
+}
@@ -2,10 +2,10 @@ package models
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
@@ -13,15 +13,22 @@ import (
 )
 
 type Response struct {
-	ID         uuid.UUID   `json:"id" db:"id"`
-	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time   `json:"updated_at" db:"updated_at"`
-	UserID     string      `json:"user_id" db:"user_id"`
-	Text       string      `json:"text" db:"text"`
-	Question   Question    `belongs_to:"question" json:"-"`
-	QuestionID uuid.UUID   `json:"question_id" db:"question_id"`
-	Answers    Answers     `many_to_many:"response_answers"`
-	AnswerIDs  []uuid.UUID `json:"answer_ids" db:"-"`
+	ID           uuid.UUID       `json:"id" db:"id"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at" db:"updated_at"`
+	UserID       string          `json:"user_id" db:"user_id"`
+	Text         string          `json:"text" db:"text"`
+	RedactedText nulls.String    `json:"redacted_text" db:"redacted_text"`
+	Redactions   RedactionCounts `json:"redactions,omitempty" db:"redactions"`
+	Question     Question        `belongs_to:"question" json:"-"`
+	QuestionID   uuid.UUID       `json:"question_id" db:"question_id"`
+	VariantID    nulls.UUID      `json:"variant_id" db:"variant_id"`
+	RunID        nulls.UUID      `json:"run_id" db:"run_id"`
+	Version      int             `json:"version" db:"version"`
+	TokenID      nulls.UUID      `json:"-" db:"token_id"`
+	Encrypted    bool            `json:"-" db:"encrypted"`
+	Answers      Answers         `many_to_many:"response_answers"`
+	AnswerIDs    []uuid.UUID     `json:"answer_ids" db:"-"`
 }
 
 // String is not required by pop and may be deleted
@@ -45,37 +52,80 @@ func (r *Response) Validate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.Validate(
 		&validators.UUIDIsPresent{Field: r.QuestionID, Name: "QuestionID"},
 		&validators.StringIsPresent{Field: r.UserID, Name: "UserID"},
-		&UserAlreadyResponded{UserID: r.UserID, QuestionID: r.QuestionID, tx: tx, Name: "UserAlreadyResponded"},
 		&IncorrectType{QuestionType: r.Question.Type, Text: r.Text, Name: "IncorrectType"},
+		&VariantBelongsToQuestion{VariantID: r.VariantID, QuestionID: r.QuestionID, tx: tx, Name: "VariantBelongsToQuestion"},
 	), nil
 }
 
 // ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
-// This method is not required and may be deleted.
+// The duplicate and quota checks run in a single validator, behind the campaign lock.
 func (r *Response) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
-	return validate.NewErrors(), nil
+	return validate.Validate(
+		&ResponseAllowed{UserID: r.UserID, QuestionID: r.QuestionID, RunID: r.RunID, AnswerIDs: r.answerIDs(), tx: tx, Name: "QuotaNotReached"},
+	), nil
 }
 
 // ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
-// This method is not required and may be deleted.
+// An edited response was already counted against the campaign and question quotas, so only the
+// answers it now selects are checked.
 func (r *Response) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
-	return validate.NewErrors(), nil
+	return validate.Validate(
+		&ResponseAllowed{UserID: r.UserID, QuestionID: r.QuestionID, AnswerIDs: r.answerIDs(), ResponseID: r.ID, tx: tx, Name: "QuotaNotReached"},
+	), nil
+}
+
+// BeforeCreate starts a new response at its first version
+func (r *Response) BeforeCreate(tx *pop.Connection) error {
+	if r.Version == 0 {
+		r.Version = 1
+	}
+	return nil
+}
+
+// AfterCreate closes the question's campaign if this response filled its quota, deletes the user's draft
+// and records the user's completion of the campaign if this was the last required question
+func (r *Response) AfterCreate(tx *pop.Connection) error {
+	question := Question{}
+	if err := tx.Find(&question, r.QuestionID); err != nil {
+		return err
+	}
+
+	if err := CloseCampaignIfFull(tx, question.CampaignID); err != nil {
+		return err
+	}
+
+	// the submitted response replaces the user's draft
+	if err := DeleteDraft(tx, r.QuestionID, r.UserID); err != nil {
+		return err
+	}
+
+	return RecordCompletion(tx, question.CampaignID, r.UserID, r.RunID)
 }
 
-type UserAlreadyResponded struct {
-	Name       string
-	UserID     string
-	QuestionID uuid.UUID
-	tx         *pop.Connection
+// answerIDs returns the IDs of the answers selected in the response
+func (r *Response) answerIDs() []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(r.Answers))
+	for _, a := range r.Answers {
+		ids = append(ids, a.ID)
+	}
+	return ids
 }
 
-func (u *UserAlreadyResponded) IsValid(errors *validate.Errors) {
-	response := Response{}
-	query := u.tx.Where("question_id = ?", u.QuestionID).Where("user_id = ?", u.UserID)
-	err := query.First(&response)
-	if err == nil {
-		errors.Add(validators.GenerateKey(u.Name), fmt.Sprintf("User %s has already responded to question %s.", u.UserID, u.QuestionID))
+// AnsweredInCampaign returns the questions a respondent has answered in a campaign.  For recurring campaigns
+// only responses in the given run count, and for other campaigns only responses outside of any run.
+func AnsweredInCampaign(tx *pop.Connection, campaignID uuid.UUID, respondentID string, runID nulls.UUID) (map[uuid.UUID]bool, error) {
+	ids := []uuid.UUID{}
+	query := `SELECT question_id FROM responses WHERE user_id = ? AND run_id <=> ?
+		AND question_id IN (SELECT id FROM questions WHERE campaign_id = ?)`
+	if err := tx.Store.Select(&ids, query, respondentID, runID, campaignID); err != nil {
+		return nil, err
+	}
+
+	answered := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		answered[id] = true
 	}
+	return answered, nil
 }
 
 type IncorrectType struct {
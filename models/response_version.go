@@ -0,0 +1,144 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// VersionUpdated marks a version that was replaced by an edit
+	VersionUpdated = "updated"
+
+	// VersionRetracted marks the last version of a response the user retracted
+	VersionRetracted = "retracted"
+)
+
+// ResponseVersion is an earlier version of a response, kept for audit when a user edits or retracts it.
+// Only the response itself is the latest version, so reports never count these.
+type ResponseVersion struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	ResponseID uuid.UUID `json:"response_id" db:"response_id"`
+	QuestionID uuid.UUID `json:"question_id" db:"question_id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	Version    int       `json:"version" db:"version"`
+	Text       string    `json:"text" db:"text"`
+	AnswerIDs  string    `json:"answer_ids" db:"answer_ids"`
+	Action     string    `json:"action" db:"action"`
+	Encrypted  bool      `json:"-" db:"encrypted"`
+}
+
+// String is not required by pop and may be deleted
+func (r ResponseVersion) String() string {
+	jr, _ := json.Marshal(r)
+	return string(jr)
+}
+
+// ResponseVersions is not required by pop and may be deleted
+type ResponseVersions []ResponseVersion
+
+// String is not required by pop and may be deleted
+func (r ResponseVersions) String() string {
+	jr, _ := json.Marshal(r)
+	return string(jr)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (r *ResponseVersion) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: r.ResponseID, Name: "ResponseID"},
+		&validators.StringInclusion{Field: r.Action, List: []string{VersionUpdated, VersionRetracted}, Name: "Action"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (r *ResponseVersion) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (r *ResponseVersion) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ArchiveResponse keeps the current version of a response, with the answers selected in it, before it is
+// edited or retracted
+func ArchiveResponse(tx *pop.Connection, r *Response, action string) error {
+	answerIDs := []uuid.UUID{}
+	if err := tx.Store.Select(&answerIDs, "SELECT answer_id FROM response_answers WHERE response_id = ? ORDER BY created_at", r.ID); err != nil {
+		return err
+	}
+
+	ids, err := json.Marshal(answerIDs)
+	if err != nil {
+		return err
+	}
+
+	version := &ResponseVersion{
+		ResponseID: r.ID,
+		QuestionID: r.QuestionID,
+		UserID:     r.UserID,
+		Version:    r.Version,
+		Text:       r.Text,
+		AnswerIDs:  string(ids),
+		Action:     action,
+	}
+
+	verrs, err := tx.ValidateAndCreate(version)
+	if err != nil {
+		return err
+	}
+
+	if verrs.HasAny() {
+		return verrs
+	}
+	return nil
+}
+
+// EditDeadline returns the time a response can no longer be edited or retracted: the end of the edit window
+// after it was submitted, when there is one, or the time its campaign, or its run, closes if that's sooner
+func EditDeadline(tx *pop.Connection, r *Response, window time.Duration) (time.Time, error) {
+	question := Question{}
+	if err := tx.Find(&question, r.QuestionID); err != nil {
+		return time.Time{}, err
+	}
+
+	campaign := Campaign{}
+	if err := tx.Find(&campaign, question.CampaignID); err != nil {
+		return time.Time{}, err
+	}
+
+	closes := []time.Time{campaign.EndDate}
+	if campaign.ClosedAt.Valid {
+		closes = append(closes, campaign.ClosedAt.Time)
+	}
+
+	if r.RunID.Valid {
+		run := CampaignRun{}
+		if err := tx.Find(&run, r.RunID.UUID); err != nil {
+			return time.Time{}, err
+		}
+		closes = append(closes, run.EndDate)
+	}
+
+	if window > 0 {
+		closes = append(closes, r.CreatedAt.Add(window))
+	}
+
+	deadline := closes[0]
+	for _, t := range closes[1:] {
+		if t.Before(deadline) {
+			deadline = t
+		}
+	}
+	return deadline, nil
+}
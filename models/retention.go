@@ -0,0 +1,164 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+)
+
+// RetentionPolicy is how many days free text and whole responses are kept.  Zero keeps them forever.
+type RetentionPolicy struct {
+	TextDays     int `json:"text_days"`
+	ResponseDays int `json:"response_days"`
+}
+
+// Retention is the campaign's retention policy, using the defaults for anything the campaign doesn't set
+func (c *Campaign) Retention(defaults RetentionPolicy) RetentionPolicy {
+	p := defaults
+	if c.TextRetentionDays.Valid {
+		p.TextDays = c.TextRetentionDays.Int
+	}
+	if c.ResponseRetentionDays.Valid {
+		p.ResponseDays = c.ResponseRetentionDays.Int
+	}
+	return p
+}
+
+// cutoff is the time before which data kept for days is purged, or nil if it is kept forever
+func cutoff(days int, now time.Time) *time.Time {
+	if days <= 0 {
+		return nil
+	}
+
+	t := now.UTC().AddDate(0, 0, -days)
+	return &t
+}
+
+// PurgeResult is what a retention policy removed from a campaign, as the number of rows with their free text
+// cleared and rows deleted in each table
+type PurgeResult struct {
+	CampaignID      uuid.UUID       `json:"campaign_id"`
+	Name            string          `json:"name"`
+	Policy          RetentionPolicy `json:"policy"`
+	TextBefore      *time.Time      `json:"text_before,omitempty"`
+	ResponsesBefore *time.Time      `json:"responses_before,omitempty"`
+	Cleared         map[string]int  `json:"cleared"`
+	Deleted         map[string]int  `json:"deleted"`
+	DryRun          bool            `json:"dry_run"`
+}
+
+// purgeStep removes data from one table, clearing the columns in clear or deleting the rows when it is
+// empty.  The where clause takes the campaign ID and the cutoff, twice when it also matches the versions of
+// old responses.
+type purgeStep struct {
+	table    string
+	clear    string
+	where    string
+	versions bool
+}
+
+const (
+	campaignResponses = "question_id IN (SELECT id FROM questions WHERE campaign_id = ?) AND created_at < ?"
+	campaignVersions  = "(" + campaignResponses + " OR response_id IN (SELECT id FROM responses WHERE " + campaignResponses + "))"
+)
+
+// textSteps clear free text while keeping the responses, so counts don't change.  Drafts are only free
+// text, so they are deleted.
+var textSteps = []purgeStep{
+	{"responses", "text = '', redacted_text = NULL", campaignResponses + " AND (text <> '' OR redacted_text IS NOT NULL)", false},
+	{"response_versions", "text = ''", campaignVersions + " AND text <> ''", true},
+	{"drafts", "", "question_id IN (SELECT id FROM questions WHERE campaign_id = ?) AND updated_at < ?", false},
+}
+
+// responseSteps delete whole responses, with their answers and earlier versions
+var responseSteps = []purgeStep{
+	{"response_answers", "", "response_id IN (SELECT id FROM responses WHERE " + campaignResponses + ")", false},
+	{"response_versions", "", campaignVersions, true},
+	{"responses", "", campaignResponses, false},
+}
+
+// run applies the step, or only counts the rows it would change for a dry run
+func (s purgeStep) run(tx *pop.Connection, campaignID uuid.UUID, before time.Time, dryRun bool) (int, error) {
+	args := []interface{}{campaignID, before}
+	if s.versions {
+		args = append(args, campaignID, before)
+	}
+
+	if dryRun {
+		var count int
+		err := tx.Store.Get(&count, "SELECT COUNT(*) FROM "+s.table+" WHERE "+s.where, args...)
+		return count, err
+	}
+
+	if s.clear != "" {
+		return tx.RawQuery("UPDATE "+s.table+" SET "+s.clear+" WHERE "+s.where, args...).ExecWithCount()
+	}
+	return tx.RawQuery("DELETE FROM "+s.table+" WHERE "+s.where, args...).ExecWithCount()
+}
+
+// Purge applies each campaign's retention policy, falling back to the defaults.  Free text is cleared before
+// whole responses are deleted, so a dry run counts the same rows as a real one.  Unless it is a dry run, each
+// campaign that had anything removed is recorded in the audit trail.
+func Purge(tx *pop.Connection, defaults RetentionPolicy, now time.Time, dryRun bool) ([]PurgeResult, error) {
+	campaigns := Campaigns{}
+	if err := tx.Order("created_at").All(&campaigns); err != nil {
+		return nil, err
+	}
+
+	results := []PurgeResult{}
+	for _, c := range campaigns {
+		policy := c.Retention(defaults)
+		result := PurgeResult{
+			CampaignID:      c.ID,
+			Name:            c.Name,
+			Policy:          policy,
+			TextBefore:      cutoff(policy.TextDays, now),
+			ResponsesBefore: cutoff(policy.ResponseDays, now),
+			Cleared:         map[string]int{},
+			Deleted:         map[string]int{},
+			DryRun:          dryRun,
+		}
+
+		if result.TextBefore == nil && result.ResponsesBefore == nil {
+			continue
+		}
+
+		removed := 0
+		for _, phase := range []struct {
+			before *time.Time
+			steps  []purgeStep
+		}{
+			{result.TextBefore, textSteps},
+			{result.ResponsesBefore, responseSteps},
+		} {
+			if phase.before == nil {
+				continue
+			}
+
+			for _, s := range phase.steps {
+				n, err := s.run(tx, c.ID, *phase.before, dryRun)
+				if err != nil {
+					return nil, err
+				}
+
+				if s.clear != "" {
+					result.Cleared[s.table] += n
+				} else {
+					result.Deleted[s.table] += n
+				}
+				removed += n
+			}
+		}
+
+		if !dryRun && removed > 0 {
+			if err := RecordAudit(tx, AuditPurge, result); err != nil {
+				return nil, err
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
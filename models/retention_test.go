@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func Test_Retention(t *testing.T) {
+	defaults := RetentionPolicy{TextDays: 180, ResponseDays: 730}
+
+	c := Campaign{}
+	if p := c.Retention(defaults); p != defaults {
+		t.Errorf("expected the defaults for a campaign without a policy, got %+v", p)
+	}
+
+	c.TextRetentionDays = nulls.NewInt(30)
+	if p := c.Retention(defaults); p.TextDays != 30 || p.ResponseDays != 730 {
+		t.Errorf("expected the campaign's text retention with the default response retention, got %+v", p)
+	}
+
+	c.ResponseRetentionDays = nulls.NewInt(365)
+	if p := c.Retention(RetentionPolicy{}); p.TextDays != 30 || p.ResponseDays != 365 {
+		t.Errorf("expected the campaign's policy without defaults, got %+v", p)
+	}
+}
+
+func Test_Cutoff(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.FixedZone("EST", -5*60*60))
+
+	if c := cutoff(0, now); c != nil {
+		t.Errorf("expected no cutoff when data is kept forever, got %s", c)
+	}
+
+	c := cutoff(30, now)
+	if c == nil || !c.Equal(time.Date(2026, 1, 30, 17, 0, 0, 0, time.UTC)) || c.Location() != time.UTC {
+		t.Errorf("expected a cutoff 30 days earlier in UTC, got %v", c)
+	}
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Section is an ordered page of questions within a campaign
+type Section struct {
+	ID          uuid.UUID    `json:"id" db:"id"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
+	Title       string       `json:"title" db:"title"`
+	Description nulls.String `json:"description" db:"description"`
+	Position    int          `json:"position" db:"position"`
+	Campaign    Campaign     `belongs_to:"campaign" json:"-"`
+	CampaignID  uuid.UUID    `json:"campaign_id" db:"campaign_id"`
+	Questions   Questions    `has_many:"questions" order_by:"position asc" json:"questions,omitempty"`
+}
+
+// String is not required by pop and may be deleted
+func (s Section) String() string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+// Sections is not required by pop and may be deleted
+type Sections []Section
+
+// String is not required by pop and may be deleted
+func (s Sections) String() string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (s *Section) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: s.Title, Name: "Title"},
+		&validators.UUIDIsPresent{Field: s.CampaignID, Name: "CampaignID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (s *Section) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (s *Section) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// SectionInCampaign is a custom validator for the section a question is placed in
+type SectionInCampaign struct {
+	Name       string
+	SectionID  nulls.UUID
+	CampaignID uuid.UUID
+	tx         *pop.Connection
+}
+
+// IsValid validates that the section, when set, belongs to the question's campaign
+func (v *SectionInCampaign) IsValid(errors *validate.Errors) {
+	if !v.SectionID.Valid {
+		return
+	}
+
+	section := Section{}
+	if err := v.tx.Find(&section, v.SectionID.UUID); err != nil {
+		errors.Add(validators.GenerateKey(v.Name), fmt.Sprintf("Section %s not found.", v.SectionID.UUID))
+		return
+	}
+
+	if section.CampaignID != v.CampaignID {
+		errors.Add(validators.GenerateKey(v.Name), fmt.Sprintf("Section %s does not belong to campaign %s.", section.ID, v.CampaignID))
+	}
+}
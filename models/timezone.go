@@ -0,0 +1,124 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	// embed the zone database so campaign time zones work on hosts without one
+	_ "time/tzdata"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+)
+
+// CampaignActiveSQL is a where clause fragment that selects campaigns inside their window and not closed.  It
+// takes the current time twice.  Like every other time in a query, the current time comes from the app rather
+// than the database's clock, and is compared in UTC since the connection's session time zone is UTC.
+const CampaignActiveSQL = "(campaigns.start_date <= ? AND campaigns.end_date > ? AND campaigns.closed_at IS NULL)"
+
+// localLayouts are the layouts accepted for campaign times given without an offset
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Location returns the campaign's time zone, UTC when it isn't set or isn't a known zone
+func (c *Campaign) Location() *time.Location {
+	if c.TimeZone == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ParseCampaignTime parses a campaign start or end time.  Times in RFC 3339 format carry their own offset.
+// Times without an offset are wall clock times in the campaign's time zone.  A wall clock time that is
+// skipped when the clocks go forward doesn't exist, so it's an error.
+func ParseCampaignTime(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC(), nil
+	}
+
+	for _, layout := range localLayouts {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err != nil {
+			continue
+		}
+
+		// time.ParseInLocation moves times in a DST gap, so they don't format back to the same wall clock
+		if t.Format(layout) != s {
+			return time.Time{}, fmt.Errorf("%s does not exist in %s", s, loc)
+		}
+		return t.UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%s is not an RFC 3339 time or a local time like 2006-01-02T15:04:05", s)
+}
+
+// UnmarshalJSON decodes a campaign, reading start_date and end_date without an offset in the campaign's time zone
+func (c *Campaign) UnmarshalJSON(data []byte) error {
+	type campaign Campaign
+	aux := struct {
+		*campaign
+		StartDate *string `json:"start_date"`
+		EndDate   *string `json:"end_date"`
+	}{campaign: (*campaign)(c)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	for _, f := range []struct {
+		value *string
+		field *time.Time
+	}{{aux.StartDate, &c.StartDate}, {aux.EndDate, &c.EndDate}} {
+		if f.value == nil {
+			continue
+		}
+
+		t, err := ParseCampaignTime(strings.TrimSpace(*f.value), c.Location())
+		if err != nil {
+			return err
+		}
+		*f.field = t
+	}
+
+	return nil
+}
+
+// BeforeSave stores the campaign window in UTC, defaulting the time zone to UTC
+func (c *Campaign) BeforeSave(tx *pop.Connection) error {
+	if c.TimeZone == "" {
+		c.TimeZone = "UTC"
+	}
+
+	c.StartDate, c.EndDate = c.StartDate.UTC(), c.EndDate.UTC()
+	return nil
+}
+
+// TimeZoneIsValid is a custom validator for campaign time zones
+type TimeZoneIsValid struct {
+	Name  string
+	Field string
+}
+
+// IsValid validates that the time zone, when set, is an IANA time zone name
+func (v *TimeZoneIsValid) IsValid(errors *validate.Errors) {
+	if v.Field == "" {
+		return
+	}
+
+	if _, err := time.LoadLocation(v.Field); err != nil || v.Field == "Local" {
+		errors.Add(validators.GenerateKey(v.Name), fmt.Sprintf("%s must be an IANA time zone name like America/New_York.", v.Name))
+	}
+}
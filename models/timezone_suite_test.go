@@ -0,0 +1,52 @@
+package models_test
+
+import (
+	"time"
+
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/nulls"
+)
+
+// activeCampaigns returns the names of the campaigns the database considers active at the given time
+func (ms *ModelSuite) activeCampaigns(now time.Time) []string {
+	campaigns := models.Campaigns{}
+	ms.NoError(models.DB.Where(models.CampaignActiveSQL, now, now).Order("name").All(&campaigns))
+
+	names := []string{}
+	for _, c := range campaigns {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func (ms *ModelSuite) Test_Timezone_SessionIsUTC() {
+	var zone string
+	ms.NoError(models.DB.Store.Get(&zone, "SELECT @@session.time_zone"))
+	ms.Equal("+00:00", zone)
+}
+
+func (ms *ModelSuite) Test_Timezone_CampaignActive() {
+	ny, err := time.LoadLocation("America/New_York")
+	ms.NoError(err)
+
+	// the clocks go forward at 2am on March 8th, so 3:30am local is 7:30am UTC
+	start, err := models.ParseCampaignTime("2026-03-08 03:30", ny)
+	ms.NoError(err)
+	end, err := models.ParseCampaignTime("2026-03-08 12:00", ny)
+	ms.NoError(err)
+
+	spring := &models.Campaign{Name: "spring", TimeZone: "America/New_York", StartDate: start, EndDate: end, Enabled: true}
+	ms.NoError(models.DB.Create(spring))
+
+	closed := &models.Campaign{Name: "closed", StartDate: start, EndDate: end, Enabled: true, ClosedAt: nulls.NewTime(start)}
+	ms.NoError(models.DB.Create(closed))
+
+	opens := time.Date(2026, 3, 8, 7, 30, 0, 0, time.UTC)
+	ms.Empty(ms.activeCampaigns(opens.Add(-time.Second)))
+	ms.Equal([]string{"spring"}, ms.activeCampaigns(opens))
+	ms.Equal([]string{"spring"}, ms.activeCampaigns(opens.In(ny)))
+
+	closes := time.Date(2026, 3, 8, 16, 0, 0, 0, time.UTC)
+	ms.Equal([]string{"spring"}, ms.activeCampaigns(closes.Add(-time.Second)))
+	ms.Empty(ms.activeCampaigns(closes))
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/validate/v3"
+)
+
+func Test_ParseCampaignTime(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		// offsets in the input win over the campaign's time zone
+		{"2026-03-09T09:00:00-04:00", time.Date(2026, time.March, 9, 13, 0, 0, 0, time.UTC)},
+		{"2026-03-09T13:00:00Z", time.Date(2026, time.March, 9, 13, 0, 0, 0, time.UTC)},
+		// 9am the Saturday before and the Monday after the clocks go forward on March 8
+		{"2026-03-07T09:00:00", time.Date(2026, time.March, 7, 14, 0, 0, 0, time.UTC)},
+		{"2026-03-09T09:00", time.Date(2026, time.March, 9, 13, 0, 0, 0, time.UTC)},
+		// and around the clocks going back on November 1
+		{"2026-10-31 09:00", time.Date(2026, time.October, 31, 13, 0, 0, 0, time.UTC)},
+		{"2026-11-02", time.Date(2026, time.November, 2, 5, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		out, err := ParseCampaignTime(tt.input, ny)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %s", tt.input, err)
+			continue
+		}
+
+		if !out.Equal(tt.expected) || out.Location() != time.UTC {
+			t.Errorf("expected %s to parse to %s, got %s", tt.input, tt.expected, out)
+		}
+	}
+
+	// 2:30am on March 8 is skipped when the clocks go forward
+	if _, err := ParseCampaignTime("2026-03-08T02:30:00", ny); err == nil {
+		t.Error("expected an error for a time in the DST gap")
+	}
+
+	for _, input := range []string{"", "next tuesday", "2026-13-01", "03/09/2026"} {
+		if _, err := ParseCampaignTime(input, ny); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func Test_CampaignUnmarshalJSON(t *testing.T) {
+	c := Campaign{}
+	body := `{"name": "spring", "time_zone": "Europe/London", "start_date": "2026-03-30T09:00:00", "end_date": "2026-04-30T17:00:00+01:00"}`
+	if err := json.Unmarshal([]byte(body), &c); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Name != "spring" || c.TimeZone != "Europe/London" {
+		t.Errorf("unexpected campaign %+v", c)
+	}
+
+	// the UK is on BST by March 30
+	if !c.StartDate.Equal(time.Date(2026, time.March, 30, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected start date %s", c.StartDate)
+	}
+
+	if !c.EndDate.Equal(time.Date(2026, time.April, 30, 16, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected end date %s", c.EndDate)
+	}
+
+	// updating other fields leaves the window alone
+	if err := json.Unmarshal([]byte(`{"enabled": true}`), &c); err != nil {
+		t.Fatal(err)
+	}
+
+	if !c.Enabled || !c.StartDate.Equal(time.Date(2026, time.March, 30, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected campaign after update %+v", c)
+	}
+
+	if err := json.Unmarshal([]byte(`{"start_date": "soon"}`), &c); err == nil {
+		t.Error("expected an error for an unparseable start date")
+	}
+}
+
+func Test_TimeZoneIsValid(t *testing.T) {
+	for zone, valid := range map[string]bool{"": true, "UTC": true, "America/New_York": true, "Local": false, "Mars/Olympus_Mons": false} {
+		errs := validate.Validate(&TimeZoneIsValid{Name: "TimeZone", Field: zone})
+		if errs.HasAny() == valid {
+			t.Errorf("expected %q valid=%t, got %v", zone, valid, errs)
+		}
+	}
+}
+
+func Test_RunWindowsAcrossDST(t *testing.T) {
+	campaign := Campaign{
+		TimeZone:           "America/New_York",
+		StartDate:          time.Date(2026, time.March, 2, 14, 0, 0, 0, time.UTC), // 9am EST
+		EndDate:            time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC),
+		Recurrence:         nulls.NewString("FREQ=WEEKLY"),
+		RunDurationSeconds: nulls.NewInt(60 * 60),
+	}
+
+	runs, err := campaign.RunWindows(campaign.EndDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runs) != 5 {
+		t.Fatalf("expected 5 runs, got %d", len(runs))
+	}
+
+	// every run starts at 9am New York time, which is an hour earlier in UTC once DST starts
+	if runs[0].StartDate.Hour() != 14 || runs[1].StartDate.Hour() != 13 || runs[4].StartDate.Hour() != 13 {
+		t.Errorf("unexpected run starts %s, %s, %s", runs[0].StartDate, runs[1].StartDate, runs[4].StartDate)
+	}
+}
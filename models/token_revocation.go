@@ -0,0 +1,79 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+)
+
+// TokenRevocation revokes the question tokens issued before RevokedAt for a question, a user or a user's
+// tokens for a question
+type TokenRevocation struct {
+	ID         uuid.UUID    `json:"id" db:"id"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at" db:"updated_at"`
+	QuestionID nulls.UUID   `json:"question_id" db:"question_id"`
+	UserID     nulls.String `json:"user_id" db:"user_id"`
+	RevokedAt  time.Time    `json:"revoked_at" db:"revoked_at"`
+}
+
+// String is not required by pop and may be deleted
+func (t TokenRevocation) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// TokenRevocations is not required by pop and may be deleted
+type TokenRevocations []TokenRevocation
+
+// String is not required by pop and may be deleted
+func (t TokenRevocations) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (t *TokenRevocation) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	verrs := validate.NewErrors()
+	if !t.QuestionID.Valid && (!t.UserID.Valid || t.UserID.String == "") {
+		verrs.Add("question_id", "A question_id or user_id is required.")
+	}
+	return verrs, nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (t *TokenRevocation) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (t *TokenRevocation) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// BeforeCreate revokes the tokens issued up to now
+func (t *TokenRevocation) BeforeCreate(tx *pop.Connection) error {
+	if t.RevokedAt.IsZero() {
+		t.RevokedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// TokenRevoked reports whether a token issued to the user for the question at issuedAt has been revoked.
+// Times are compared to the second, so a token issued in the same second as a revocation is revoked.
+func TokenRevoked(tx *pop.Connection, userID string, questionID uuid.UUID, issuedAt time.Time) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM token_revocations WHERE revoked_at >= ?
+		AND (question_id IS NULL OR question_id = ?) AND (user_id IS NULL OR user_id IN (?, ?))`
+	if err := tx.Store.Get(&count, query, issuedAt.UTC().Truncate(time.Second), questionID, userID, SuppressionID(userID)); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
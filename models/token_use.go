@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// TokenUse records that the question token with the ID was used to submit a response, so it can't be
+// used to submit another
+type TokenUse struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	QuestionID uuid.UUID `json:"question_id" db:"question_id"`
+}
+
+// String is not required by pop and may be deleted
+func (t TokenUse) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// TokenUses is not required by pop and may be deleted
+type TokenUses []TokenUse
+
+// String is not required by pop and may be deleted
+func (t TokenUses) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (t *TokenUse) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: t.ID, Name: "ID"},
+		&validators.UUIDIsPresent{Field: t.QuestionID, Name: "QuestionID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (t *TokenUse) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (t *TokenUse) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// UseToken records that a token was used, returning false if it had been used already.  A second use of
+// the same token waits on the first one's transaction, so a token can't be used twice at once.
+func UseToken(tx *pop.Connection, jti, questionID uuid.UUID) (bool, error) {
+	now := time.Now().UTC()
+	n, err := tx.RawQuery("INSERT IGNORE INTO token_uses (id, question_id, created_at, updated_at) VALUES (?, ?, ?, ?)", jti, questionID, now, now).ExecWithCount()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,290 @@
+package models
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+)
+
+// userTables are the tables holding a user's rows, with the where clause that finds them given a user ID.
+// Rows that feed reports are pseudonymized when a user is erased, so counts don't change, and their free
+// text columns cleared.  Rows matching suppress keep the user from being surveyed, or their revoked tokens
+// from working, so they are kept under the user's SuppressionID instead.  The rest only matter to the user
+// and are deleted.  Any table added later that holds a user ID has to be listed here, and exported in
+// ExportUserData.  Token uses come first, since they are found through the responses that used them.
+var userTables = []struct {
+	table        string
+	where        string
+	pseudonymize bool
+	clear        string
+	suppress     string
+}{
+	{"token_uses", "id IN (SELECT token_id FROM responses WHERE user_id = ?)", false, "", ""},
+	{"responses", "user_id = ?", true, "text = '', redacted_text = NULL, token_id = NULL", ""},
+	{"response_versions", "user_id = ?", true, "text = ''", ""},
+	{"campaign_completions", "user_id = ?", true, "", ""},
+	{"dismissals", "user_id = ?", true, "", ""},
+	{"impressions", "user_id = ?", true, "", ""},
+	{"campaign_samples", "user_id = ?", true, "", ""},
+	{"drafts", "user_id = ?", false, "", ""},
+	{"variant_assignments", "user_id = ?", false, "", ""},
+	{"campaign_user_lists", "user_id = ?", false, "", "list = 'deny'"},
+	{"opt_outs", "user_id = ?", false, "", "TRUE"},
+	{"token_revocations", "user_id = ?", false, "", "TRUE"},
+	{"rate_limits", "bucket = CONCAT('user:', ?)", false, "", ""},
+}
+
+// respondentIDs returns the IDs a user's rows are stored under: their user ID, and the hash of it for each
+// anonymous campaign
+func respondentIDs(tx *pop.Connection, userID string) ([]string, error) {
+	campaigns := Campaigns{}
+	if err := tx.Where("anonymous = true").Order("created_at").All(&campaigns); err != nil {
+		return nil, err
+	}
+
+	ids := []string{userID}
+	for _, c := range campaigns {
+		ids = append(ids, c.RespondentID(userID))
+	}
+	return ids, nil
+}
+
+// auditReference is how a user ID appears in the detail of an audit event, such as a token revocation
+func auditReference(userID string) (string, error) {
+	ju, err := json.Marshal(userID)
+	if err != nil {
+		return "", err
+	}
+	return `"user_id":` + string(ju), nil
+}
+
+// RateLimitBucket is the state of a rate limit bucket
+type RateLimitBucket struct {
+	Bucket     string     `json:"bucket" db:"bucket"`
+	Tokens     float64    `json:"tokens" db:"tokens"`
+	RefilledAt nulls.Time `json:"refilled_at" db:"refilled_at"`
+}
+
+// UserExport is everything stored about a user.  Rows in anonymous campaigns are only included in the
+// export handed to the user themselves; admins get the number of them in each table instead.
+type UserExport struct {
+	UserID      string              `json:"user_id"`
+	ExportedAt  time.Time           `json:"exported_at"`
+	Responses   []HistoryEntry      `json:"responses"`
+	Versions    ResponseVersions    `json:"response_versions"`
+	Drafts      Drafts              `json:"drafts"`
+	Dismissals  Dismissals          `json:"dismissals"`
+	Completions CampaignCompletions `json:"campaign_completions"`
+	Impressions Impressions         `json:"impressions"`
+	Samples     []CampaignSample    `json:"campaign_samples"`
+	Assignments VariantAssignments  `json:"variant_assignments"`
+	Lists       []CampaignUserList  `json:"campaign_user_lists"`
+	OptOuts     []OptOut            `json:"opt_outs"`
+	TokenUses   TokenUses           `json:"token_uses"`
+	Revocations TokenRevocations    `json:"token_revocations"`
+	RateLimits  []RateLimitBucket   `json:"rate_limits"`
+	AuditEvents AuditEvents         `json:"audit_events"`
+	Anonymous   map[string]int      `json:"anonymous_rows,omitempty"`
+}
+
+// ExportUserData collects everything stored about a user.  Their rows in anonymous campaigns are only
+// included when anonymous is set, which is only done for the user themselves, so admins can't read a named
+// user's anonymous answers.  Otherwise only the number of those rows in each table is reported.
+func ExportUserData(tx *pop.Connection, userID string, anonymous bool) (*UserExport, error) {
+	ids, err := respondentIDs(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var hidden map[string]int
+	if !anonymous {
+		if hidden, err = countUserRows(tx, ids[1:]); err != nil {
+			return nil, err
+		}
+		ids = ids[:1]
+	}
+
+	export := &UserExport{
+		UserID:      userID,
+		ExportedAt:  time.Now().UTC(),
+		Responses:   []HistoryEntry{},
+		Versions:    ResponseVersions{},
+		Drafts:      Drafts{},
+		Dismissals:  Dismissals{},
+		Completions: CampaignCompletions{},
+		Impressions: Impressions{},
+		Samples:     []CampaignSample{},
+		Assignments: VariantAssignments{},
+		Lists:       []CampaignUserList{},
+		OptOuts:     []OptOut{},
+		TokenUses:   TokenUses{},
+		Revocations: TokenRevocations{},
+		RateLimits:  []RateLimitBucket{},
+		AuditEvents: AuditEvents{},
+		Anonymous:   hidden,
+	}
+
+	for _, id := range ids {
+		responses, err := ResponseHistory(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		export.Responses = append(export.Responses, responses...)
+	}
+
+	sort.SliceStable(export.Responses, func(i, j int) bool {
+		return export.Responses[i].CreatedAt.After(export.Responses[j].CreatedAt)
+	})
+
+	args := stringArgs(ids)
+	for _, rows := range []interface{}{&export.Versions, &export.Drafts, &export.Dismissals, &export.Completions, &export.Impressions, &export.Samples, &export.Assignments, &export.Lists, &export.OptOuts, &export.Revocations} {
+		if err := tx.Where("user_id IN (?)", args...).Order("created_at").All(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Where("id IN (SELECT token_id FROM responses WHERE user_id IN (?))", args...).Order("created_at").All(&export.TokenUses); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Store.Select(&export.RateLimits, "SELECT bucket, tokens, refilled_at FROM rate_limits WHERE bucket = ?", "user:"+userID); err != nil {
+		return nil, err
+	}
+
+	ref, err := auditReference(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("LOCATE(?, detail) > 0", ref).Order("created_at").All(&export.AuditEvents); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// countUserRows counts the rows stored under the IDs in each per-user table, leaving out the empty ones
+func countUserRows(tx *pop.Connection, ids []string) (map[string]int, error) {
+	counts := map[string]int{}
+	for _, t := range userTables {
+		for _, id := range ids {
+			var n int
+			if err := tx.Store.Get(&n, "SELECT COUNT(*) FROM "+t.table+" WHERE "+t.where, id); err != nil {
+				return nil, err
+			}
+
+			if n > 0 {
+				counts[t.table] += n
+			}
+		}
+	}
+	return counts, nil
+}
+
+// ErasureReceipt records what was done to erase a user, as the number of rows deleted, pseudonymized or
+// kept under the user's suppression hash in each table
+type ErasureReceipt struct {
+	UserID        string         `json:"user_id"`
+	ErasedAt      time.Time      `json:"erased_at"`
+	Deleted       map[string]int `json:"deleted"`
+	Pseudonymized map[string]int `json:"pseudonymized"`
+	Suppressed    map[string]int `json:"suppressed"`
+}
+
+// EraseUserData removes a user from the database, including their rows in anonymous campaigns.  Rows that
+// feed reports have the user ID replaced with a random pseudonym, the same one for all of the user's rows
+// so counts of distinct users don't change, and their free text cleared.  Rows stored under the hash for an
+// anonymous campaign get a pseudonym of their own, so erasing a user doesn't link their anonymous responses
+// to anything else.  Nothing links the pseudonyms back to the user.  Opt outs, deny list entries and token
+// revocations are kept under the user's SuppressionID, so they still apply if the user comes back.
+// Everything else is deleted, and audit events that name the user are changed to name the pseudonym instead.
+func EraseUserData(tx *pop.Connection, userID string) (*ErasureReceipt, error) {
+	ids, err := respondentIDs(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &ErasureReceipt{
+		UserID:        userID,
+		ErasedAt:      time.Now().UTC(),
+		Deleted:       map[string]int{},
+		Pseudonymized: map[string]int{},
+		Suppressed:    map[string]int{},
+	}
+
+	var pseudonym string
+	for i, id := range ids {
+		uid, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+
+		p := "forgotten-" + uid.String()
+		if i == 0 {
+			pseudonym = p
+		}
+
+		for _, t := range userTables {
+			// suppression entries are only stored under the user ID.  IGNORE leaves the ones the hash
+			// already has, from an earlier erasure, to be deleted below.
+			if t.suppress != "" && i == 0 {
+				n, err := tx.RawQuery("UPDATE IGNORE "+t.table+" SET user_id = ? WHERE "+t.where+" AND "+t.suppress, SuppressionID(userID), id).ExecWithCount()
+				if err != nil {
+					return nil, err
+				}
+				receipt.Suppressed[t.table] += n
+			}
+
+			if !t.pseudonymize {
+				n, err := tx.RawQuery("DELETE FROM "+t.table+" WHERE "+t.where, id).ExecWithCount()
+				if err != nil {
+					return nil, err
+				}
+				receipt.Deleted[t.table] += n
+				continue
+			}
+
+			query := "UPDATE " + t.table + " SET user_id = ?"
+			if t.clear != "" {
+				query += ", " + t.clear
+			}
+
+			n, err := tx.RawQuery(query+" WHERE "+t.where, p, id).ExecWithCount()
+			if err != nil {
+				return nil, err
+			}
+			receipt.Pseudonymized[t.table] += n
+		}
+	}
+
+	ref, err := auditReference(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	replacement, err := auditReference(pseudonym)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := tx.RawQuery("UPDATE audit_events SET detail = REPLACE(detail, ?, ?) WHERE LOCATE(?, detail) > 0", ref, replacement, ref).ExecWithCount()
+	if err != nil {
+		return nil, err
+	}
+	receipt.Pseudonymized["audit_events"] = n
+
+	return receipt, nil
+}
+
+// stringArgs converts a list of strings to query arguments
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		args = append(args, v)
+	}
+	return args
+}
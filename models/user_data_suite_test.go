@@ -0,0 +1,172 @@
+package models_test
+
+import (
+	"time"
+
+	"github.com/YaleSpinup/tweaser/fixtures"
+	"github.com/YaleSpinup/tweaser/models"
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
+)
+
+// seedUser stores a row for the user in every per-user table, in a plain and an anonymous campaign, and
+// returns the IDs their rows are stored under
+func (ms *ModelSuite) seedUser(userID string) []string {
+	plain := fixtures.Campaign(ms.T(), "plain", 0)
+	anonymous := &models.Campaign{Name: "anonymous", StartDate: plain.StartDate, EndDate: plain.EndDate, Enabled: true, Anonymous: true}
+	ms.NoError(models.DB.Create(anonymous))
+
+	ids := []string{}
+	for _, c := range []*models.Campaign{plain, anonymous} {
+		id := c.RespondentID(userID)
+		ids = append(ids, id)
+
+		answered := fixtures.Question(ms.T(), c, "answered", 0, 0)
+		drafted := fixtures.Question(ms.T(), c, "drafted", 0, 0)
+
+		jti := uuid.Must(uuid.NewV4())
+		fresh, err := models.UseToken(models.DB, jti, answered.ID)
+		ms.NoError(err)
+		ms.True(fresh)
+
+		response := &models.Response{UserID: id, QuestionID: answered.ID, Text: "first", TokenID: nulls.NewUUID(jti)}
+		ms.NoError(models.DB.Create(response))
+		ms.NoError(models.ArchiveResponse(models.DB, response, models.VersionUpdated))
+		ms.NoError(models.DB.Create(&models.Response{UserID: id, QuestionID: drafted.ID, Text: "sent"}))
+		ms.NoError(models.DB.Create(&models.Draft{UserID: id, QuestionID: drafted.ID, Text: "after"}))
+		ms.NoError(models.DB.Create(&models.Dismissal{UserID: id, QuestionID: drafted.ID}))
+		ms.NoError(models.DB.Create(&models.Impression{UserID: id, QuestionID: answered.ID, CampaignID: c.ID}))
+		ms.NoError(models.RecordSample(models.DB, c.ID, id, true))
+		ms.NoError(models.DB.Create(&models.VariantAssignment{UserID: id, QuestionID: answered.ID}))
+	}
+
+	ms.NoError(models.DB.Create(&models.CampaignUserList{CampaignID: plain.ID, List: "allow", UserID: userID}))
+	ms.NoError(models.DB.Create(&models.CampaignUserList{CampaignID: anonymous.ID, List: "deny", UserID: userID}))
+	ms.NoError(models.DB.Create(&models.OptOut{UserID: userID, Category: "surveys"}))
+
+	revocation := &models.TokenRevocation{UserID: nulls.NewString(userID), RevokedAt: time.Now()}
+	ms.NoError(models.DB.Create(revocation))
+	ms.NoError(models.RecordAudit(models.DB, models.AuditRevokeTokens, revocation))
+
+	ms.NoError(models.DB.RawQuery("INSERT INTO rate_limits (bucket, tokens, refilled_at) VALUES (?, 1, NULL)", "user:"+userID).Exec())
+
+	return ids
+}
+
+// userRows counts the rows left in each per-user table for the IDs
+func (ms *ModelSuite) userRows(ids []string) map[string]int {
+	left := map[string]int{}
+	for table, where := range models.UserTableWheres() {
+		for _, id := range ids {
+			var n int
+			ms.NoError(models.DB.Store.Get(&n, "SELECT COUNT(*) FROM "+table+" WHERE "+where, id))
+			left[table] += n
+		}
+	}
+	return left
+}
+
+func (ms *ModelSuite) Test_UserData_EveryTableIsErased() {
+	// every table with a user_id column has to be listed, so new tables aren't missed
+	tables := []string{}
+	ms.NoError(models.DB.Store.Select(&tables, "SELECT table_name FROM information_schema.columns WHERE table_schema = DATABASE() AND column_name = 'user_id'"))
+	ms.NotEmpty(tables)
+
+	wheres := models.UserTableWheres()
+	for _, table := range tables {
+		ms.Contains(wheres, table)
+	}
+}
+
+func (ms *ModelSuite) Test_UserData_Erase() {
+	ids := ms.seedUser("someguy")
+	ms.seedUser("someotherguy")
+
+	for table, n := range ms.userRows(ids) {
+		ms.NotZero(n, "expected the user to have rows in %s", table)
+	}
+
+	receipt, err := models.EraseUserData(models.DB, "someguy")
+	ms.NoError(err)
+	ms.Equal(2, receipt.Deleted["token_uses"])
+	ms.Equal(4, receipt.Pseudonymized["responses"])
+	ms.Equal(1, receipt.Pseudonymized["audit_events"])
+	ms.Equal(1, receipt.Suppressed["campaign_user_lists"])
+	ms.Equal(1, receipt.Suppressed["opt_outs"])
+	ms.Equal(1, receipt.Suppressed["token_revocations"])
+	ms.Equal(1, receipt.Deleted["campaign_user_lists"])
+
+	for table, n := range ms.userRows(ids) {
+		ms.Zero(n, "expected nothing left in %s", table)
+	}
+
+	var refs int
+	ms.NoError(models.DB.Store.Get(&refs, "SELECT COUNT(*) FROM audit_events WHERE LOCATE(?, detail) > 0", `"user_id":"someguy"`))
+	ms.Zero(refs)
+
+	// free text is cleared from the pseudonymized rows, and the other user is left alone
+	var text int
+	ms.NoError(models.DB.Store.Get(&text, "SELECT COUNT(*) FROM responses WHERE user_id LIKE 'forgotten-%' AND text <> ''"))
+	ms.Zero(text)
+
+	others, err := models.DB.Where("user_id = ?", "someotherguy").Count(&models.Responses{})
+	ms.NoError(err)
+	ms.Equal(2, others)
+
+	// the user stays opted out and denied if they come back, and their revoked tokens stay revoked
+	suppressed := models.SuppressionID("someguy")
+	lists, err := models.DB.Where("user_id = ? AND list = 'deny'", suppressed).Count(&models.CampaignUserLists{})
+	ms.NoError(err)
+	ms.Equal(1, lists)
+
+	optOuts, err := models.DB.Where("user_id = ?", suppressed).Count(&models.OptOuts{})
+	ms.NoError(err)
+	ms.Equal(1, optOuts)
+
+	revoked, err := models.TokenRevoked(models.DB, "someguy", uuid.Must(uuid.NewV4()), time.Now().Add(-time.Hour))
+	ms.NoError(err)
+	ms.True(revoked)
+
+	// the plain and anonymous rows are pseudonymized separately
+	var pseudonyms int
+	ms.NoError(models.DB.Store.Get(&pseudonyms, "SELECT COUNT(DISTINCT user_id) FROM responses WHERE user_id LIKE 'forgotten-%'"))
+	ms.Equal(2, pseudonyms)
+}
+
+func (ms *ModelSuite) Test_UserData_Export() {
+	ms.seedUser("someguy")
+	ms.seedUser("someotherguy")
+
+	export, err := models.ExportUserData(models.DB, "someguy", true)
+	ms.NoError(err)
+	ms.Len(export.Responses, 4)
+	ms.Len(export.Versions, 2)
+	ms.Len(export.Drafts, 2)
+	ms.Len(export.Dismissals, 2)
+	ms.Len(export.Completions, 2)
+	ms.Len(export.Impressions, 2)
+	ms.Len(export.Samples, 2)
+	ms.Len(export.Lists, 2)
+	ms.Len(export.OptOuts, 1)
+	ms.Len(export.TokenUses, 2)
+	ms.Len(export.Revocations, 1)
+	ms.Len(export.RateLimits, 1)
+	ms.Len(export.AuditEvents, 1)
+}
+
+func (ms *ModelSuite) Test_UserData_AdminExport() {
+	ms.seedUser("someguy")
+
+	// admins only get the number of rows in anonymous campaigns, never their contents
+	export, err := models.ExportUserData(models.DB, "someguy", false)
+	ms.NoError(err)
+	ms.Len(export.Responses, 2)
+	ms.Len(export.Versions, 1)
+	ms.Len(export.Drafts, 1)
+	ms.Len(export.TokenUses, 1)
+	ms.Equal(2, export.Anonymous["responses"])
+	ms.Equal(1, export.Anonymous["drafts"])
+	for _, r := range export.Responses {
+		ms.Equal("plain", r.CampaignName)
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// VariantAssignment records the wording of a question a user was assigned the first time it was served to
+// them, so enabling or disabling variants later doesn't move them to another one.  A null VariantID is the
+// question's own text.
+type VariantAssignment struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	QuestionID uuid.UUID  `json:"question_id" db:"question_id"`
+	UserID     string     `json:"user_id" db:"user_id"`
+	VariantID  nulls.UUID `json:"variant_id" db:"variant_id"`
+}
+
+// String is not required by pop and may be deleted
+func (v VariantAssignment) String() string {
+	jv, _ := json.Marshal(v)
+	return string(jv)
+}
+
+// VariantAssignments is not required by pop and may be deleted
+type VariantAssignments []VariantAssignment
+
+// String is not required by pop and may be deleted
+func (v VariantAssignments) String() string {
+	jv, _ := json.Marshal(v)
+	return string(jv)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (v *VariantAssignment) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: v.QuestionID, Name: "QuestionID"},
+		&validators.StringIsPresent{Field: v.UserID, Name: "UserID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (v *VariantAssignment) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (v *VariantAssignment) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// FindVariantAssignment returns the user's assignment for a question, or nil if it was never served to them
+// with variants
+func FindVariantAssignment(tx *pop.Connection, questionID uuid.UUID, userID string) (*VariantAssignment, error) {
+	assignment := &VariantAssignment{}
+	if err := tx.Where("question_id = ?", questionID).Where("user_id = ?", userID).First(assignment); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return assignment, nil
+}
@@ -0,0 +1,7 @@
+package models
+
+import "testing"
+
+func Test_VariantAssignment(t *testing.T) {
+	t.Log("This test needs to be implemented!")
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Lockout locks a key out for Duration once it has failed Threshold times within Window, like an IP
+// guessing at a password
+type Lockout struct {
+	Threshold int
+	Window    time.Duration
+	Duration  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*lockoutEntry
+	calls   int
+}
+
+type lockoutEntry struct {
+	failures    int
+	first       time.Time
+	lockedUntil time.Time
+}
+
+// NewLockout creates a lockout with no failures recorded
+func NewLockout(threshold int, window, duration time.Duration) *Lockout {
+	return &Lockout{Threshold: threshold, Window: window, Duration: duration, entries: map[string]*lockoutEntry{}}
+}
+
+// Locked reports whether the key is locked out, and if so for how much longer
+func (l *Lockout) Locked(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok || !now.Before(e.lockedUntil) {
+		return false, 0
+	}
+	return true, e.lockedUntil.Sub(now)
+}
+
+// Fail records a failure for the key, returning the failures counted in the current window and whether
+// the key is now locked out.  Failures start counting again once the window or lockout has passed.
+func (l *Lockout) Fail(key string, now time.Time) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.calls++
+	if l.calls%sweepEvery == 0 {
+		for k, e := range l.entries {
+			if l.expired(e, now) {
+				delete(l.entries, k)
+			}
+		}
+	}
+
+	e, ok := l.entries[key]
+	if !ok || l.expired(e, now) {
+		e = &lockoutEntry{first: now}
+		l.entries[key] = e
+	}
+
+	e.failures++
+	if e.failures >= l.Threshold {
+		e.lockedUntil = now.Add(l.Duration)
+		return e.failures, true
+	}
+	return e.failures, false
+}
+
+// Succeed forgets the key's failures
+func (l *Lockout) Succeed(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+}
+
+// expired reports whether the entry's window and any lockout have passed
+func (l *Lockout) expired(e *lockoutEntry, now time.Time) bool {
+	return now.Sub(e.first) >= l.Window && !now.Before(e.lockedUntil)
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockout(t *testing.T) {
+	l := NewLockout(3, time.Minute, 10*time.Minute)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 1; i < 3; i++ {
+		if n, locked := l.Fail("10.0.0.1", now); n != i || locked {
+			t.Fatalf("expected failure %d not to lock out, got %d %v", i, n, locked)
+		}
+	}
+
+	if n, locked := l.Fail("10.0.0.1", now.Add(30*time.Second)); n != 3 || !locked {
+		t.Fatalf("expected the third failure to lock out, got %d %v", n, locked)
+	}
+
+	if locked, wait := l.Locked("10.0.0.1", now.Add(time.Minute)); !locked || wait != 9*time.Minute+30*time.Second {
+		t.Errorf("expected to be locked out for another 9m30s, got %v %s", locked, wait)
+	}
+
+	if locked, _ := l.Locked("10.0.0.2", now); locked {
+		t.Error("expected other keys not to be locked out")
+	}
+
+	if locked, _ := l.Locked("10.0.0.1", now.Add(11*time.Minute)); locked {
+		t.Error("expected the lockout to end")
+	}
+
+	if n, _ := l.Fail("10.0.0.1", now.Add(11*time.Minute)); n != 1 {
+		t.Errorf("expected failures to start over after the lockout, got %d", n)
+	}
+}
+
+func TestLockoutWindow(t *testing.T) {
+	l := NewLockout(2, time.Minute, time.Hour)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	l.Fail("10.0.0.1", now)
+	if n, locked := l.Fail("10.0.0.1", now.Add(2*time.Minute)); n != 1 || locked {
+		t.Errorf("expected failures outside the window not to add up, got %d %v", n, locked)
+	}
+
+	l.Succeed("10.0.0.1")
+	if n, _ := l.Fail("10.0.0.1", now.Add(2*time.Minute)); n != 1 {
+		t.Errorf("expected a success to clear the failures, got %d", n)
+	}
+}
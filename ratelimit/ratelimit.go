@@ -0,0 +1,123 @@
+// Package ratelimit implements token bucket rate limiting.
+//
+// Each key, such as a client IP or user ID, has a bucket holding up to Burst tokens that refills at Rate
+// tokens a second.  A request takes a token and is refused while the bucket is empty.  Buckets are kept
+// in a Store, in memory for a single server or in a shared store when requests are spread over replicas.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy is how quickly a bucket refills and how many tokens it holds
+type Policy struct {
+	Rate  float64
+	Burst int
+}
+
+// ParsePolicy parses a policy given as requests per period, like 30/1m.  The bucket holds the number of
+// requests allowed in one period.  An empty policy is disabled.
+func ParsePolicy(s string) (Policy, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Policy{}, nil
+	}
+
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Policy{}, fmt.Errorf("rate limit %q must be given as requests/period, like 30/1m", s)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || n < 1 {
+		return Policy{}, fmt.Errorf("rate limit %q must allow at least 1 request", s)
+	}
+
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q must have a positive period, like 1m", s)
+	}
+
+	return Policy{Rate: float64(n) / period.Seconds(), Burst: n}, nil
+}
+
+// Enabled reports whether the policy limits anything
+func (p Policy) Enabled() bool {
+	return p.Rate > 0 && p.Burst > 0
+}
+
+// Bucket is the state of one key's bucket
+type Bucket struct {
+	Tokens float64
+	Last   time.Time
+}
+
+// Take refills the bucket for the time since it was last used and takes a token from it.  When the bucket
+// is empty it is returned unchanged along with how long until the next token.  A zero bucket is full.
+func (p Policy) Take(b Bucket, now time.Time) (Bucket, bool, time.Duration) {
+	tokens := float64(p.Burst)
+	if !b.Last.IsZero() {
+		tokens = math.Min(float64(p.Burst), b.Tokens+now.Sub(b.Last).Seconds()*p.Rate)
+	}
+
+	if tokens < 1 {
+		wait := time.Duration((1 - tokens) / p.Rate * float64(time.Second))
+		return b, false, wait
+	}
+
+	return Bucket{Tokens: tokens - 1, Last: now}, true, 0
+}
+
+// full reports whether the bucket would have refilled completely by now, so it can be forgotten
+func (p Policy) full(b Bucket, now time.Time) bool {
+	return b.Tokens+now.Sub(b.Last).Seconds()*p.Rate >= float64(p.Burst)
+}
+
+// Store keeps buckets for a policy
+type Store interface {
+	// Take takes a token from the key's bucket, returning whether it was allowed and if not how long
+	// until it would be
+	Take(key string, p Policy, now time.Time) (bool, time.Duration, error)
+}
+
+// Memory keeps buckets in memory.  Buckets that have refilled are swept out every so often so keys that
+// stop making requests don't build up, which assumes every bucket in the store follows the same policy.
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]Bucket
+	calls   int
+}
+
+// sweepEvery is how many calls to Take are made between sweeps of the buckets that have refilled
+const sweepEvery = 1000
+
+// NewMemory creates an empty in memory store
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]Bucket{}}
+}
+
+// Take takes a token from the key's bucket
+func (m *Memory) Take(key string, p Policy, now time.Time) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls++
+	if m.calls%sweepEvery == 0 {
+		for k, b := range m.buckets {
+			if p.full(b, now) {
+				delete(m.buckets, k)
+			}
+		}
+	}
+
+	b, ok, wait := p.Take(m.buckets[key], now)
+	if ok {
+		m.buckets[key] = b
+	}
+	return ok, wait, nil
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("30/1m")
+	if err != nil || p.Burst != 30 || p.Rate != 0.5 {
+		t.Errorf("expected 30 tokens refilling at 0.5/s, got %+v (%v)", p, err)
+	}
+
+	if p, err := ParsePolicy(""); err != nil || p.Enabled() {
+		t.Errorf("expected an empty policy to be disabled, got %+v (%v)", p, err)
+	}
+
+	for _, s := range []string{"30", "0/1m", "x/1m", "30/0s", "30/soon"} {
+		if _, err := ParsePolicy(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestTake(t *testing.T) {
+	p := Policy{Rate: 1, Burst: 2}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	b, ok, _ := p.Take(Bucket{}, now)
+	if !ok || b.Tokens != 1 {
+		t.Fatalf("expected a full bucket to allow a request, got %+v", b)
+	}
+
+	b, ok, _ = p.Take(b, now)
+	if !ok || b.Tokens != 0 {
+		t.Fatalf("expected the burst to allow a second request, got %+v", b)
+	}
+
+	_, ok, wait := p.Take(b, now.Add(250*time.Millisecond))
+	if ok || wait != 750*time.Millisecond {
+		t.Errorf("expected an empty bucket to refuse the request for 750ms, got %v %s", ok, wait)
+	}
+
+	b, ok, _ = p.Take(b, now.Add(time.Second))
+	if !ok || b.Tokens != 0 {
+		t.Errorf("expected a token after a second, got %+v", b)
+	}
+
+	b, _, _ = p.Take(b, now.Add(time.Hour))
+	if b.Tokens != 1 {
+		t.Errorf("expected the bucket to refill no further than the burst, got %+v", b)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	p := Policy{Rate: 1, Burst: 1}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if ok, _, _ := m.Take("10.0.0.1", p, now); !ok {
+		t.Error("expected the first request to be allowed")
+	}
+
+	if ok, wait, _ := m.Take("10.0.0.1", p, now); ok || wait != time.Second {
+		t.Errorf("expected the second request to wait a second, got %v %s", ok, wait)
+	}
+
+	if ok, _, _ := m.Take("10.0.0.2", p, now); !ok {
+		t.Error("expected another key to have its own bucket")
+	}
+
+	later := now.Add(time.Minute)
+	for i := 0; i < sweepEvery; i++ {
+		m.Take(fmt.Sprintf("user%d", i), p, later)
+	}
+
+	if _, ok := m.buckets["10.0.0.1"]; ok {
+		t.Error("expected buckets that refilled to be swept")
+	}
+}
@@ -0,0 +1,148 @@
+// Package redaction finds personal details in free text and replaces them with a placeholder naming
+// what was found.
+//
+// A Pipeline runs detectors over the text in order, each one seeing the text redacted by the ones before
+// it.  The built in detectors find email addresses, US Social Security numbers and phone numbers.  Other
+// patterns are given one per line as a name and a regular expression:
+//
+//	netid = \b[a-z]{2,3}[0-9]{1,5}\b
+//	ticket = (?i)\bINC[0-9]{7}\b
+//
+// Matches are replaced with [redacted:name].
+package redaction
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Detector finds one kind of personal detail in text
+type Detector interface {
+	// Name names what the detector finds, it is used in the placeholder and when counting redactions
+	Name() string
+
+	// Find returns the start and end of each match, like regexp.FindAllStringIndex
+	Find(text string) [][]int
+}
+
+// Pattern is a detector that matches a regular expression
+type Pattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// NewPattern compiles a regular expression into a detector
+func NewPattern(name, expr string) (*Pattern, error) {
+	if name == "" || strings.ContainsAny(name, "[]= \t") {
+		return nil, fmt.Errorf("pattern name %q must be a single word", name)
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("pattern %s: %s", name, err)
+	}
+
+	if re.MatchString("") {
+		return nil, fmt.Errorf("pattern %s matches empty text", name)
+	}
+
+	return &Pattern{name: name, re: re}, nil
+}
+
+func mustPattern(name, expr string) *Pattern {
+	p, err := NewPattern(name, expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Name names what the pattern finds
+func (p *Pattern) Name() string {
+	return p.name
+}
+
+// Find returns the start and end of each match of the pattern
+func (p *Pattern) Find(text string) [][]int {
+	return p.re.FindAllStringIndex(text, -1)
+}
+
+var (
+	// Email finds email addresses
+	Email Detector = mustPattern("email", `(?i)\b[a-z0-9._%+\-]+@[a-z0-9\-]+(?:\.[a-z0-9\-]+)*\.[a-z]{2,}\b`)
+
+	// SSN finds numbers formatted like US Social Security numbers
+	SSN Detector = mustPattern("ssn", `\b[0-9]{3}[- ][0-9]{2}[- ][0-9]{4}\b`)
+
+	// Phone finds North American phone numbers, with or without a country code
+	Phone Detector = mustPattern("phone", `(?:\+?1[ .\-]?)?(?:\([0-9]{3}\)|\b[0-9]{3})[ .\-]?[0-9]{3}[ .\-]?[0-9]{4}\b`)
+)
+
+// Builtin is the pipeline of built in detectors.  Email addresses are redacted first so the digits in them
+// aren't taken for phone numbers.
+func Builtin() Pipeline {
+	return Pipeline{Email, SSN, Phone}
+}
+
+// Pipeline is a list of detectors run in order
+type Pipeline []Detector
+
+// ParsePatterns parses patterns given one per line as name = expression.  Blank lines and lines starting
+// with # are skipped.
+func ParsePatterns(spec string) (Pipeline, error) {
+	p := Pipeline{}
+	seen := map[string]bool{}
+	for i, line := range strings.Split(spec, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d must be given as name = expression", i+1)
+		}
+
+		name := strings.TrimSpace(parts[0])
+		if seen[name] {
+			return nil, fmt.Errorf("line %d: pattern %s is defined more than once", i+1, name)
+		}
+		seen[name] = true
+
+		pattern, err := NewPattern(name, strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		p = append(p, pattern)
+	}
+
+	return p, nil
+}
+
+// Redact replaces everything the detectors find with a placeholder, returning the redacted text and the
+// number of redactions made by each detector.  Detectors that found nothing aren't counted.
+func (p Pipeline) Redact(text string) (string, map[string]int) {
+	counts := map[string]int{}
+	for _, d := range p {
+		matches := d.Find(text)
+		if len(matches) == 0 {
+			continue
+		}
+
+		placeholder := "[redacted:" + d.Name() + "]"
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			b.WriteString(text[last:m[0]])
+			b.WriteString(placeholder)
+			last = m[1]
+		}
+		b.WriteString(text[last:])
+
+		text = b.String()
+		counts[d.Name()] += len(matches)
+	}
+
+	return text, counts
+}
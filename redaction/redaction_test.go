@@ -0,0 +1,84 @@
+package redaction
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuiltin(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+		counts   map[string]int
+	}{
+		{
+			text:     "nothing to see here",
+			expected: "nothing to see here",
+			counts:   map[string]int{},
+		},
+		{
+			text:     "email me at some.guy+tweaser@mail.example.edu or someguy@example.com.",
+			expected: "email me at [redacted:email] or [redacted:email].",
+			counts:   map[string]int{"email": 2},
+		},
+		{
+			text:     "call (203) 555-1234, 203.555.1234 or +1 203 555 1234",
+			expected: "call [redacted:phone], [redacted:phone] or [redacted:phone]",
+			counts:   map[string]int{"phone": 3},
+		},
+		{
+			text:     "my ssn is 123-45-6789 and my extension is 1234",
+			expected: "my ssn is [redacted:ssn] and my extension is 1234",
+			counts:   map[string]int{"ssn": 1},
+		},
+		{
+			text:     "2035551234@txt.example.com",
+			expected: "[redacted:email]",
+			counts:   map[string]int{"email": 1},
+		},
+		{
+			text:     "we have 1200 servers and 35 databases",
+			expected: "we have 1200 servers and 35 databases",
+			counts:   map[string]int{},
+		},
+	}
+
+	for _, test := range tests {
+		out, counts := Builtin().Redact(test.text)
+		if out != test.expected {
+			t.Errorf("expected %q for %q, got %q", test.expected, test.text, out)
+		}
+
+		if !reflect.DeepEqual(counts, test.counts) {
+			t.Errorf("expected counts %v for %q, got %v", test.counts, test.text, counts)
+		}
+	}
+}
+
+func TestParsePatterns(t *testing.T) {
+	p, err := ParsePatterns("# yale netids\nnetid = \\b[a-z]{2,3}[0-9]{1,5}\\b\n\nticket=(?i)\\bINC[0-9]{7}\\b\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, counts := p.Redact("ask abc123 about inc0001234")
+	if out != "ask [redacted:netid] about [redacted:ticket]" {
+		t.Errorf("unexpected redaction %q", out)
+	}
+
+	if !reflect.DeepEqual(counts, map[string]int{"netid": 1, "ticket": 1}) {
+		t.Errorf("unexpected counts %v", counts)
+	}
+
+	for _, spec := range []string{
+		"netid",
+		"net id = [a-z]+",
+		"netid = [a-z",
+		"netid = [a-z]*",
+		"netid = a\nnetid = b",
+	} {
+		if _, err := ParsePatterns(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
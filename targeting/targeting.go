@@ -0,0 +1,430 @@
+// Package targeting implements the small expression language used to target campaigns at users
+// based on the attributes passed along with a request for their questions.
+//
+// A rule compares attributes to quoted strings and combines the comparisons with boolean operators:
+//
+//	role == "faculty" && (product == "containers" || product in ["servers", "databases"])
+//
+// The operators are == and != for equality, in and not in for list membership, and !, && and ||
+// (or not, and and or) to combine them.  An attribute on its own is true when it was passed with a
+// non-empty value.  Comparisons are case sensitive and an attribute that wasn't passed is "".
+package targeting
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Rule is a compiled targeting rule
+type Rule struct {
+	src  string
+	root node
+}
+
+// Attributes are the user attributes a rule is evaluated against
+type Attributes map[string]string
+
+// Compile parses a rule.  An empty rule matches everyone.
+func Compile(src string) (*Rule, error) {
+	p := &parser{lexer: lexer{src: src}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokEOF {
+		return &Rule{src: src}, nil
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+
+	return &Rule{src: src, root: root}, nil
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]*Rule{}
+)
+
+// CompileCached compiles a rule once and reuses it for every later call with the same source
+func CompileCached(src string) (*Rule, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	if rule, ok := cache[src]; ok {
+		return rule, nil
+	}
+
+	rule, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+
+	cache[src] = rule
+	return rule, nil
+}
+
+// Match evaluates the rule against the attributes
+func (r *Rule) Match(attrs Attributes) bool {
+	if r.root == nil {
+		return true
+	}
+	return r.root.eval(attrs)
+}
+
+// String returns the source of the rule
+func (r *Rule) String() string {
+	return r.src
+}
+
+type node interface {
+	eval(Attributes) bool
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(a Attributes) bool { return n.left.eval(a) || n.right.eval(a) }
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(a Attributes) bool { return n.left.eval(a) && n.right.eval(a) }
+
+type notNode struct{ expr node }
+
+func (n notNode) eval(a Attributes) bool { return !n.expr.eval(a) }
+
+type presentNode struct{ attr string }
+
+func (n presentNode) eval(a Attributes) bool { return a[n.attr] != "" }
+
+type equalNode struct {
+	attr   string
+	value  string
+	negate bool
+}
+
+func (n equalNode) eval(a Attributes) bool { return (a[n.attr] == n.value) != n.negate }
+
+type inNode struct {
+	attr   string
+	values []string
+	negate bool
+}
+
+func (n inNode) eval(a Attributes) bool {
+	for _, v := range n.values {
+		if a[n.attr] == v {
+			return !n.negate
+		}
+	}
+	return n.negate
+}
+
+type parser struct {
+	lexer
+	tok token
+}
+
+func (p *parser) next() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("targeting rule: position %d: %s", p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+// parseOr parses and_expr { "||" and_expr }
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokOr {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses unary { "&&" unary }
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokAnd {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+// parseUnary parses "!" unary | "(" or_expr ")" | comparison
+func (p *parser) parseUnary() (node, error) {
+	switch p.tok.kind {
+	case tokNot:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{expr}, nil
+	case tokLParen:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected ) but found %s", p.tok)
+		}
+		return expr, p.next()
+	case tokIdent:
+		return p.parseComparison()
+	}
+
+	return nil, p.errorf("expected an attribute, ( or ! but found %s", p.tok)
+}
+
+// parseComparison parses ident [ ("==" | "!=") string | ["not"] "in" list ]
+func (p *parser) parseComparison() (node, error) {
+	attr := p.tok.text
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	switch p.tok.kind {
+	case tokEq, tokNe:
+		negate := p.tok.kind == tokNe
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		value, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return equalNode{attr: attr, value: value, negate: negate}, nil
+	case tokNot:
+		// only "not in" can follow an attribute
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		if p.tok.kind != tokIn {
+			return nil, p.errorf("expected in after not but found %s", p.tok)
+		}
+		return p.parseIn(attr, true)
+	case tokIn:
+		return p.parseIn(attr, false)
+	}
+
+	return presentNode{attr: attr}, nil
+}
+
+// parseIn parses "in" "[" string { "," string } "]"
+func (p *parser) parseIn(attr string, negate bool) (node, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokLBracket {
+		return nil, p.errorf("expected [ but found %s", p.tok)
+	}
+
+	values := []string{}
+	for {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		value, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		if p.tok.kind == tokRBracket {
+			return inNode{attr: attr, values: values, negate: negate}, p.next()
+		}
+
+		if p.tok.kind != tokComma {
+			return nil, p.errorf("expected , or ] but found %s", p.tok)
+		}
+	}
+}
+
+func (p *parser) parseString() (string, error) {
+	if p.tok.kind != tokString {
+		return "", p.errorf("expected a quoted string but found %s", p.tok)
+	}
+
+	value := p.tok.text
+	return value, p.next()
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokEq
+	tokNe
+	tokAnd
+	tokOr
+	tokNot
+	tokIn
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of rule"
+	case tokString:
+		return fmt.Sprintf("%q", t.text)
+	}
+	return t.text
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+var keywords = map[string]tokenKind{
+	"and": tokAnd,
+	"or":  tokOr,
+	"not": tokNot,
+	"in":  tokIn,
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	two := ""
+	if l.pos+1 < len(l.src) {
+		two = l.src[l.pos : l.pos+2]
+	}
+
+	switch two {
+	case "==":
+		l.pos += 2
+		return token{kind: tokEq, text: two, pos: start}, nil
+	case "!=":
+		l.pos += 2
+		return token{kind: tokNe, text: two, pos: start}, nil
+	case "&&":
+		l.pos += 2
+		return token{kind: tokAnd, text: two, pos: start}, nil
+	case "||":
+		l.pos += 2
+		return token{kind: tokOr, text: two, pos: start}, nil
+	}
+
+	ch := l.src[l.pos]
+	single := map[byte]tokenKind{'!': tokNot, '(': tokLParen, ')': tokRParen, '[': tokLBracket, ']': tokRBracket, ',': tokComma}
+	if kind, ok := single[ch]; ok {
+		l.pos++
+		return token{kind: kind, text: string(ch), pos: start}, nil
+	}
+
+	if ch == '"' || ch == '\'' {
+		return l.lexString(ch)
+	}
+
+	if isIdentChar(ch) {
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.pos++
+		}
+
+		text := l.src[start:l.pos]
+		if kind, ok := keywords[strings.ToLower(text)]; ok {
+			return token{kind: kind, text: text, pos: start}, nil
+		}
+		return token{kind: tokIdent, text: text, pos: start}, nil
+	}
+
+	return token{}, fmt.Errorf("targeting rule: position %d: unexpected character %q", start+1, ch)
+}
+
+func (l *lexer) lexString(quote byte) (token, error) {
+	start := l.pos
+	l.pos++
+
+	var sb strings.Builder
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		switch {
+		case ch == '\\' && l.pos+1 < len(l.src):
+			sb.WriteByte(l.src[l.pos+1])
+			l.pos += 2
+		case ch == quote:
+			l.pos++
+			return token{kind: tokString, text: sb.String(), pos: start}, nil
+		default:
+			sb.WriteByte(ch)
+			l.pos++
+		}
+	}
+
+	return token{}, fmt.Errorf("targeting rule: position %d: unterminated string", start+1)
+}
+
+func isIdentChar(ch byte) bool {
+	return ch == '_' || ch == '-' || ch == '.' || ch == ':' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
@@ -0,0 +1,86 @@
+package targeting
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	attrs := Attributes{"role": "faculty", "product": "containers", "school": "medicine"}
+
+	tests := []struct {
+		rule     string
+		expected bool
+	}{
+		{``, true},
+		{`role == "faculty"`, true},
+		{`role == 'student'`, false},
+		{`role != "student"`, true},
+		{`role == "faculty" && product == "containers"`, true},
+		{`role == "faculty" and product == "servers"`, false},
+		{`role == "student" || product == "containers"`, true},
+		{`role == "student" or product == "servers"`, false},
+		{`product in ["servers", "containers"]`, true},
+		{`product not in ["servers", "containers"]`, false},
+		{`product IN ["servers"]`, false},
+		{`!(role == "staff")`, true},
+		{`not role == "faculty"`, false},
+		{`school`, true},
+		{`department`, false},
+		{`!department && (role == "staff" || (school == "medicine" && product in ["containers"]))`, true},
+		{`department == ""`, true},
+		{`role == "fac\"ulty"`, false},
+	}
+
+	for _, tt := range tests {
+		rule, err := Compile(tt.rule)
+		if err != nil {
+			t.Errorf("unexpected error compiling %s: %s", tt.rule, err)
+			continue
+		}
+
+		if got := rule.Match(attrs); got != tt.expected {
+			t.Errorf("expected %s to be %t, got %t", tt.rule, tt.expected, got)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	rules := []string{
+		`role ==`,
+		`role == faculty`,
+		`(role == "faculty"`,
+		`role == "faculty")`,
+		`role == "faculty" &&`,
+		`product in "containers"`,
+		`product in ["containers"`,
+		`product in ["containers" "servers"]`,
+		`product not ["containers"]`,
+		`role == "faculty`,
+		`role = "faculty"`,
+		`&& role`,
+	}
+
+	for _, r := range rules {
+		if _, err := Compile(r); err == nil {
+			t.Errorf("expected an error compiling %s", r)
+		}
+	}
+}
+
+func TestCompileCached(t *testing.T) {
+	first, err := CompileCached(`role == "faculty"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := CompileCached(`role == "faculty"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != second {
+		t.Error("expected the cached rule to be reused")
+	}
+
+	if _, err := CompileCached(`role ==`); err == nil {
+		t.Error("expected an error compiling a bad rule")
+	}
+}